@@ -17,23 +17,572 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DefaultTankImage is run by tanks that don't specify their own container.
+const DefaultTankImage = "wernight/funbox"
+
+// DefaultLocation is where aquaria are built when spec.location doesn't say otherwise.
+const DefaultLocation = "pier39"
+
+// DefaultTargetCPUUtilizationPercentage is the CPU utilization the autoscaler
+// keeps the tanks at when spec.autoscaling doesn't say otherwise.
+const DefaultTargetCPUUtilizationPercentage = 80
+
+// DefaultDegradedThreshold is the share of ready tanks needed for an aquarium
+// to be considered Kinda healthy when spec.health doesn't say otherwise.
+var DefaultDegradedThreshold = intstr.FromString("50%")
+
+// DefaultTeardownTimeout is how long tanks get to drain when spec.teardown doesn't say otherwise.
+var DefaultTeardownTimeout = metav1.Duration{Duration: 5 * time.Minute}
+
+// DefaultWaterQualityWindow is how far back water readings are considered
+// when spec.water_quality doesn't say otherwise.
+var DefaultWaterQualityWindow = metav1.Duration{Duration: time.Hour}
+
+// DefaultWaterQuality are the ranges of a tropical freshwater tank, used for
+// every parameter spec.water_quality doesn't set a range for.
+var DefaultWaterQuality = WaterQualitySpec{
+	PH: &Range{Min: resource.NewScaledQuantity(65, -1), Max: resource.NewScaledQuantity(85, -1)},
+	TemperatureCelsius: &Range{
+		Min: resource.NewQuantity(22, resource.DecimalSI),
+		Max: resource.NewQuantity(28, resource.DecimalSI),
+	},
+	AmmoniaPPM: &Range{Max: resource.NewScaledQuantity(25, -2)},
+	NitratePPM: &Range{Max: resource.NewQuantity(40, resource.DecimalSI)},
+}
+
+// DefaultTankCommand is the command run by tanks using DefaultTankImage.
+var DefaultTankCommand = []string{"sleep", "10000"}
+
+// DefaultTankMountPath is where the volume of a tank is mounted when
+// spec.storage doesn't say otherwise.
+const DefaultTankMountPath = "/var/lib/tank"
+
+// DefaultCanaryTanks is the number of tanks a canary rollout updates first
+// when spec.rollout doesn't say otherwise.
+const DefaultCanaryTanks int32 = 1
+
 // AquariumSpec defines the desired state of Aquarium
 type AquariumSpec struct {
 	// +kubebuilder:validation:Minimum=1
 	NumTanks int32 `json:"num_tanks,omitempty"`
 	// +kubebuilder:default=pier39
 	Location string `json:"location,omitempty"`
+
+	// Tank is the template for the container running in every tank.
+	// Leaving it out runs the default tank image of the operator.
+	Tank TankTemplate `json:"tank,omitempty"`
+
+	// Health configures how fish health is derived from the tanks.
+	// +optional
+	Health HealthSpec `json:"health,omitempty"`
+
+	// Autoscaling lets a HorizontalPodAutoscaler owned by the aquarium scale
+	// the tanks. While it is set num_tanks is not applied to the tanks.
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// Teardown configures how the aquarium is drained when it is deleted.
+	// +optional
+	Teardown TeardownSpec `json:"teardown,omitempty"`
+
+	// WaterQuality sets the ranges the water readings of the tanks must stay in.
+	// +optional
+	WaterQuality WaterQualitySpec `json:"water_quality,omitempty"`
+
+	// Maintenance schedules the windows in which the tanks are cleaned.
+	// +optional
+	Maintenance MaintenanceSpec `json:"maintenance,omitempty"`
+
+	// Schedule overrides num_tanks while one of its entries is active, like
+	// during opening hours. When several entries are active the first one
+	// wins. The schedule is ignored while autoscaling.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Schedule []ScheduleEntry `json:"schedule,omitempty"`
+
+	// DriftPolicy is what the operator does about changes made to the tanks'
+	// Deployment outside of it. Defaults to Enforce. StatefulSet tanks are
+	// always enforced, so only Enforce is allowed with workload_kind StatefulSet.
+	// +kubebuilder:validation:Enum=Enforce;Report;Ignore
+	// +kubebuilder:default=Enforce
+	// +optional
+	DriftPolicy DriftPolicy `json:"drift_policy,omitempty"`
+
+	// Rollout configures how the tanks are updated to a new tank template.
+	// +optional
+	Rollout RolloutSpec `json:"rollout,omitempty"`
+
+	// Disruption configures how many tanks keep running through voluntary
+	// disruptions, like node drains.
+	// +optional
+	Disruption DisruptionSpec `json:"disruption,omitempty"`
+
+	// Exposure serves a viewing endpoint for visitors from the tanks.
+	// +optional
+	Exposure *ExposureSpec `json:"exposure,omitempty"`
+
+	// WorkloadKind is the kind of workload running the tanks. StatefulSet
+	// tanks have a stable identity and, with spec.storage, a volume each.
+	// Changing it moves the tanks to the new kind once it is ready.
+	// Defaults to Deployment.
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet
+	// +kubebuilder:default=Deployment
+	// +optional
+	WorkloadKind WorkloadKind `json:"workload_kind,omitempty"`
+
+	// Storage gives every tank a persistent volume of its own. It is only
+	// used by StatefulSet tanks, and can't be changed while they run.
+	// +optional
+	Storage *StorageSpec `json:"storage,omitempty"`
+}
+
+// WorkloadKind is the kind of workload running the tanks.
+type WorkloadKind string
+
+const (
+	// WorkloadDeployment runs interchangeable tanks in a Deployment.
+	WorkloadDeployment WorkloadKind = "Deployment"
+	// WorkloadStatefulSet runs tanks with a stable identity and storage in a StatefulSet.
+	WorkloadStatefulSet WorkloadKind = "StatefulSet"
+)
+
+// StorageSpec describes the persistent volume of every tank.
+type StorageSpec struct {
+	// Size of the volume of each tank, like 1Gi.
+	Size resource.Quantity `json:"size"`
+
+	// StorageClassName is the class the volumes are provisioned from.
+	// Defaults to the cluster's default class.
+	// +optional
+	StorageClassName *string `json:"storage_class_name,omitempty"`
+
+	// MountPath is where the volume is mounted in the tank container.
+	// Defaults to /var/lib/tank.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	MountPath string `json:"mount_path,omitempty"`
+}
+
+// ExposureRoute is how visitors are routed to the Service of the tanks.
+type ExposureRoute string
+
+const (
+	// RouteIngress routes visitors through an Ingress.
+	RouteIngress ExposureRoute = "Ingress"
+	// RouteHTTPRoute routes visitors through a Gateway API HTTPRoute.
+	RouteHTTPRoute ExposureRoute = "HTTPRoute"
+)
+
+// ExposureSpec configures the viewing endpoint of an aquarium: a Service in
+// front of the tanks and, optionally, a route to it.
+type ExposureSpec struct {
+	// Port is the port the tank container serves visitors on.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// ServiceType is the type of the Service in front of the tanks.
+	// Defaults to ClusterIP.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +optional
+	ServiceType corev1.ServiceType `json:"service_type,omitempty"`
+
+	// Route routes visitors to the Service through an Ingress or an
+	// HTTPRoute. Without one only the Service is created.
+	// +kubebuilder:validation:Enum=Ingress;HTTPRoute
+	// +optional
+	Route ExposureRoute `json:"route,omitempty"`
+
+	// Hostname is the host visitors reach the aquarium at, like
+	// bay.aquarium.example. Routes without one match every host.
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+
+	// Path is the path prefix visitors reach the aquarium at. Defaults to /.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// IngressClassName is the class of the Ingress. Defaults to the
+	// cluster's default class.
+	// +optional
+	IngressClassName *string `json:"ingress_class_name,omitempty"`
+
+	// Gateway is the Gateway the HTTPRoute attaches to. Required for
+	// HTTPRoute.
+	// +optional
+	Gateway *GatewayReference `json:"gateway,omitempty"`
+}
+
+// GatewayReference refers to a Gateway API Gateway.
+type GatewayReference struct {
+	// Name of the Gateway.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the Gateway. Defaults to the namespace of the aquarium.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// SectionName is the listener of the Gateway to attach to. Defaults to
+	// every listener.
+	// +optional
+	SectionName string `json:"section_name,omitempty"`
+}
+
+// DisruptionSpec configures the PodDisruptionBudget of the tanks.
+type DisruptionSpec struct {
+	// MinAvailableTanks is the number of tanks that keep running through
+	// voluntary disruptions. Defaults to every tank but one, and is capped
+	// there so node drains can always make progress.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinAvailableTanks *int32 `json:"min_available_tanks,omitempty"`
+}
+
+// RolloutStrategy is how the tanks are updated to a new tank template.
+type RolloutStrategy string
+
+const (
+	// RolloutRollingUpdate replaces the tanks a few at a time.
+	RolloutRollingUpdate RolloutStrategy = "RollingUpdate"
+	// RolloutRecreate stops every tank before the new ones start.
+	RolloutRecreate RolloutStrategy = "Recreate"
+	// RolloutCanary updates a few canary tanks and holds back the others
+	// until the new template is promoted.
+	RolloutCanary RolloutStrategy = "Canary"
+)
+
+// RolloutSpec configures how the tanks are updated to a new tank template.
+type RolloutSpec struct {
+	// Strategy is how the tanks are updated. Defaults to RollingUpdate.
+	// A Canary rollout runs the new template in canary_tanks tanks of a
+	// separate <aquarium>-canary Deployment until the aquarium is annotated
+	// with fun.tydanny.com/promote: "true", and then rolls it out to the
+	// other tanks like a RollingUpdate.
+	// +kubebuilder:validation:Enum=RollingUpdate;Recreate;Canary
+	// +optional
+	Strategy RolloutStrategy `json:"strategy,omitempty"`
+
+	// MaxSurge is the number or percentage of tanks that may run above the
+	// desired number while the tanks roll. Defaults to 25%.
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:validation:Pattern=`^[0-9]+%$`
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"max_surge,omitempty"`
+
+	// MaxUnavailable is the number or percentage of tanks that may be
+	// unavailable while the tanks roll. Defaults to 25%.
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:validation:Pattern=`^(100|[1-9]?[0-9])%$`
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"max_unavailable,omitempty"`
+
+	// CanaryTanks is the number of tanks a Canary rollout updates before the
+	// new template is promoted. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CanaryTanks *int32 `json:"canary_tanks,omitempty"`
+}
+
+// DriftPolicy is what the operator does about changes made to the tanks'
+// Deployment outside of it.
+type DriftPolicy string
+
+const (
+	// DriftEnforce reports changes and reverts them.
+	DriftEnforce DriftPolicy = "Enforce"
+	// DriftReport reports changes and keeps them until the aquarium changes.
+	DriftReport DriftPolicy = "Report"
+	// DriftIgnore keeps changes until the aquarium changes without reporting them.
+	DriftIgnore DriftPolicy = "Ignore"
+)
+
+// ScheduleEntry sets the number of tanks for a recurring period of time.
+type ScheduleEntry struct {
+	// Name of the entry, like opening-hours.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Start is the cron expression the entry becomes active on, like "0 9 * * 1-5".
+	// +kubebuilder:validation:MinLength=1
+	Start string `json:"start"`
+
+	// Duration is how long the entry stays active, like 8h.
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone is the time zone the start is interpreted in, like Europe/Berlin.
+	// Defaults to the time zone of the operator.
+	// +optional
+	TimeZone *string `json:"time_zone,omitempty"`
+
+	// Tanks is the number of tanks while the entry is active.
+	// +kubebuilder:validation:Minimum=0
+	Tanks int32 `json:"tanks"`
+}
+
+// MaintenanceSpec configures the maintenance windows of an aquarium. During a
+// window the tanks are scaled down to a floor and the fish are at most Kinda
+// healthy. The tanks scale back up once the window is over.
+type MaintenanceSpec struct {
+	// Windows are the recurring maintenance windows.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Windows []MaintenanceWindow `json:"windows,omitempty"`
+
+	// Tanks is the number of tanks kept running during a window. Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Tanks *int32 `json:"tanks,omitempty"`
+
+	// TimeZone is the time zone the window schedules are interpreted in.
+	// Defaults to the time zone of the operator.
+	// +optional
+	TimeZone *string `json:"time_zone,omitempty"`
+}
+
+// MaintenanceWindow is a recurring period of maintenance.
+type MaintenanceWindow struct {
+	// Name of the window, like weekly-cleaning.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Schedule is the cron expression the window starts on, like "0 6 * * 1".
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window lasts, like 2h.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// WaterQualitySpec configures how water readings are judged. Parameters
+// without a range use the ranges of a tropical freshwater tank.
+type WaterQualitySpec struct {
+	// Window is how far back readings are averaged. Older readings are
+	// pruned. Defaults to 1h.
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// PH is the acceptable pH. Defaults to 6.5-8.5.
+	// +optional
+	PH *Range `json:"ph,omitempty"`
+
+	// TemperatureCelsius is the acceptable temperature in degrees Celsius.
+	// Defaults to 22-28.
+	// +optional
+	TemperatureCelsius *Range `json:"temperature_celsius,omitempty"`
+
+	// AmmoniaPPM is the acceptable ammonia concentration in parts per million.
+	// Defaults to at most 0.25.
+	// +optional
+	AmmoniaPPM *Range `json:"ammonia_ppm,omitempty"`
+
+	// NitratePPM is the acceptable nitrate concentration in parts per million.
+	// Defaults to at most 40.
+	// +optional
+	NitratePPM *Range `json:"nitrate_ppm,omitempty"`
+}
+
+// Range is an inclusive range of values. Either end may be left open.
+type Range struct {
+	// +optional
+	Min *resource.Quantity `json:"min,omitempty"`
+	// +optional
+	Max *resource.Quantity `json:"max,omitempty"`
+}
+
+// TeardownSpec configures the graceful teardown of an aquarium.
+type TeardownSpec struct {
+	// Timeout is how long the operator waits for the tanks to drain before it
+	// gives up and lets the aquarium go. Defaults to 5m.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// AutoscalingSpec configures the HorizontalPodAutoscaler of an aquarium.
+type AutoscalingSpec struct {
+	// MinTanks is the lower limit for the number of tanks. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinTanks *int32 `json:"min_tanks,omitempty"`
+
+	// MaxTanks is the upper limit for the number of tanks.
+	// +kubebuilder:validation:Minimum=1
+	MaxTanks int32 `json:"max_tanks"`
+
+	// TargetCPUUtilizationPercentage is the average CPU utilization of the
+	// tanks, relative to their requests, that the autoscaler aims for.
+	// The tank template must request CPU for it to have any effect.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=80
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"target_cpu_utilization_percentage,omitempty"`
+}
+
+// HealthSpec configures the fish health evaluation.
+type HealthSpec struct {
+	// DegradedThreshold is the number or percentage of ready tanks at which
+	// the aquarium is still Kinda healthy. Below it the fish are Unhealthy.
+	// Defaults to 50%.
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:validation:Pattern=`^(100|[1-9]?[0-9])%$`
+	// +optional
+	DegradedThreshold *intstr.IntOrString `json:"degraded_threshold,omitempty"`
+}
+
+// TankTemplate describes the container that runs in each tank.
+type TankTemplate struct {
+	// Image is the container image for the tank. Left empty, the tank runs
+	// the default tank image of the operator, wernight/funbox unless it is
+	// configured otherwise.
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Command overrides the image entrypoint.
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// Args are passed to the command.
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// Env is the list of environment variables set in the tank container.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Resources are the compute resources required by each tank.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// ImagePullPolicy is the pull policy for the tank image.
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	// +kubebuilder:default=IfNotPresent
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"image_pull_policy,omitempty"`
+
+	// ImagePullSecrets are references to secrets used to pull the tank image.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"image_pull_secrets,omitempty"`
 }
 
 // AquariumStatus defines the observed state of Aquarium
 type AquariumStatus struct {
+	// Conditions are the Available, Progressing, Degraded, ReconcileError and
+	// aquariumReady observations of the aquarium.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
+	// ObservedGeneration is the most recent generation reconciled by the operator.
+	// +optional
+	ObservedGeneration int64 `json:"observed_generation,omitempty"`
+
 	NumTanksReady int32      `json:"num_tanks_ready,omitempty"`
 	FishHealth    FishHealth `json:"fish_health,omitempty"`
+
+	// Selector is the label selector of the tanks, used by the scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// Population sums up the fish placed in the aquarium.
+	// +optional
+	Population Population `json:"population,omitempty"`
+
+	// EffectiveTanks is the number of tanks the operator last asked for, after
+	// the schedule, maintenance and autoscaling are taken into account.
+	// +optional
+	EffectiveTanks int32 `json:"effective_tanks,omitempty"`
+
+	// ActiveSchedule is the name of the schedule entry in effect, empty when
+	// num_tanks is.
+	// +optional
+	ActiveSchedule string `json:"active_schedule,omitempty"`
+
+	// Rollout counts the tanks running the latest and older tank templates.
+	// +optional
+	Rollout RolloutStatus `json:"rollout,omitempty"`
+
+	// URL is the address visitors reach the aquarium at, once it has one.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Tanks reports on the pod of every tank, sick tanks first. At most
+	// MaxTankStatuses tanks are listed.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Tanks []TankStatus `json:"tanks,omitempty"`
+}
+
+// MaxTankStatuses caps the number of tanks listed in the status of an aquarium.
+const MaxTankStatuses = 20
+
+// TankStatus reports on the pod of a tank.
+type TankStatus struct {
+	// Name of the pod of the tank.
+	Name string `json:"name"`
+
+	// Node the tank runs on, empty until it is scheduled.
+	// +optional
+	Node string `json:"node,omitempty"`
+
+	// Phase of the pod of the tank.
+	Phase corev1.PodPhase `json:"phase"`
+
+	// Ready is true when the tank is ready.
+	Ready bool `json:"ready"`
+
+	// RestartCount is the number of times the tank container restarted.
+	RestartCount int32 `json:"restart_count"`
+
+	// LastTerminationReason is why the tank container last terminated, like OOMKilled.
+	// +optional
+	LastTerminationReason string `json:"last_termination_reason,omitempty"`
+}
+
+// RolloutStatus counts the tanks running the latest and older tank templates.
+type RolloutStatus struct {
+	// NewTemplateTanks is the number of tanks running the latest tank template.
+	NewTemplateTanks int32 `json:"new_template_tanks"`
+
+	// OldTemplateTanks is the number of tanks still running an older tank template.
+	OldTemplateTanks int32 `json:"old_template_tanks"`
+
+	// AwaitingPromotion is true while canary tanks run the latest template
+	// and the other tanks wait for it to be promoted.
+	// +optional
+	AwaitingPromotion bool `json:"awaiting_promotion,omitempty"`
+}
+
+// Population sums up the fish living in an aquarium.
+type Population struct {
+	// TotalFish is the number of fish across all species.
+	TotalFish int32 `json:"total_fish"`
+
+	// Species is the number of fish per species, sorted by species.
+	// +listType=map
+	// +listMapKey=species
+	// +optional
+	Species []SpeciesCount `json:"species,omitempty"`
+}
+
+// SpeciesCount is the number of fish of a species.
+type SpeciesCount struct {
+	Species string `json:"species"`
+	Count   int32  `json:"count"`
 }
 
 type FishHealth string
@@ -47,9 +596,19 @@ const (
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.num_tanks,statuspath=.status.num_tanks_ready,selectorpath=.status.selector
 // +kubebuilder:validation:Required
 // +kubebuilder:printcolumn:name="Tanks",type="integer",JSONPath=".status.num_tanks_ready",priority=0
+// +kubebuilder:printcolumn:name="Desired",type="integer",JSONPath=".spec.num_tanks",priority=1
+// +kubebuilder:printcolumn:name="Effective",type="integer",JSONPath=".status.effective_tanks",priority=1
+// +kubebuilder:printcolumn:name="Schedule",type="string",JSONPath=".status.active_schedule",priority=1
+// +kubebuilder:printcolumn:name="New",type="integer",JSONPath=".status.rollout.new_template_tanks",priority=1
+// +kubebuilder:printcolumn:name="Old",type="integer",JSONPath=".status.rollout.old_template_tanks",priority=1
+// +kubebuilder:printcolumn:name="Workload",type="string",JSONPath=".spec.workload_kind",priority=1
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".status.url",priority=1
 // +kubebuilder:printcolumn:name="Fish Health",type="string",JSONPath=".status.fish_health",priority=0
+// +kubebuilder:printcolumn:name="Fish",type="integer",JSONPath=".status.population.total_fish",priority=1
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"aquariumReady\")].reason",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",priority=0
 
 // Aquarium is the Schema for the aquaria API
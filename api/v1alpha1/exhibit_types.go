@@ -0,0 +1,131 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExhibitSpec defines the desired state of Exhibit
+type ExhibitSpec struct {
+	// Template is the aquarium built at every location of the exhibit.
+	Template AquariumTemplate `json:"template"`
+
+	// Locations are where the exhibit is shown. An aquarium named after the
+	// exhibit and the location is built at each of them.
+	// +listType=map
+	// +listMapKey=location
+	// +kubebuilder:validation:MinItems=1
+	Locations []ExhibitLocation `json:"locations"`
+}
+
+// AquariumTemplate describes the aquaria of an exhibit.
+type AquariumTemplate struct {
+	// Labels are put on every aquarium of the exhibit.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are put on every aquarium of the exhibit.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Spec of the aquaria. The location is set per aquarium.
+	Spec AquariumSpec `json:"spec"`
+}
+
+// ExhibitLocation is a location an exhibit is shown at.
+type ExhibitLocation struct {
+	// Location of the aquarium, like pier39.
+	// +kubebuilder:validation:MinLength=1
+	Location string `json:"location"`
+
+	// NumTanks overrides the number of tanks of the template at this location.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	NumTanks *int32 `json:"num_tanks,omitempty"`
+}
+
+// ExhibitStatus defines the observed state of Exhibit
+type ExhibitStatus struct {
+	// Conditions are the Ready observations of the exhibit.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the most recent generation reconciled by the operator.
+	// +optional
+	ObservedGeneration int64 `json:"observed_generation,omitempty"`
+
+	// FishHealth rolls up the fish health of the exhibit's aquaria. It is
+	// Healthy when all of them are, Unhealthy when none of them is at least
+	// Kinda healthy, and Kinda in between.
+	// +optional
+	FishHealth FishHealth `json:"fish_health,omitempty"`
+
+	// HealthyLocations is the number of locations with healthy fish.
+	// +optional
+	HealthyLocations int32 `json:"healthy_locations,omitempty"`
+
+	// Locations reports on the aquarium at every location.
+	// +listType=map
+	// +listMapKey=location
+	// +optional
+	Locations []ExhibitLocationStatus `json:"locations,omitempty"`
+}
+
+// ExhibitLocationStatus reports on the aquarium of an exhibit at a location.
+type ExhibitLocationStatus struct {
+	Location string `json:"location"`
+
+	// Aquarium is the name of the aquarium built at the location.
+	Aquarium string `json:"aquarium"`
+
+	// +optional
+	NumTanksReady int32 `json:"num_tanks_ready,omitempty"`
+
+	// +optional
+	FishHealth FishHealth `json:"fish_health,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Healthy",type="integer",JSONPath=".status.healthy_locations",priority=0,description="Locations with healthy fish"
+// +kubebuilder:printcolumn:name="Fish Health",type="string",JSONPath=".status.fish_health",priority=0
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",priority=0
+
+// Exhibit is the Schema for the exhibits API
+type Exhibit struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ExhibitSpec   `json:"spec,omitempty"`
+	Status ExhibitStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ExhibitList contains a list of Exhibit
+type ExhibitList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Exhibit `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Exhibit{}, &ExhibitList{})
+}
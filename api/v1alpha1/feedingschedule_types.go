@@ -0,0 +1,152 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultFeederImage is the image the feeding jobs run.
+const DefaultFeederImage = "busybox:1.36"
+
+// DefaultFeedingGracePeriod is how late a feeding may start before it counts
+// as missed when the schedule doesn't say otherwise.
+var DefaultFeedingGracePeriod = metav1.Duration{Duration: 5 * time.Minute}
+
+// FeedingScheduleSpec defines the desired state of FeedingSchedule
+type FeedingScheduleSpec struct {
+	// Aquarium is the name of the aquarium in the same namespace that is fed.
+	// +kubebuilder:validation:MinLength=1
+	Aquarium string `json:"aquarium"`
+
+	// Feedings are the meals served to the tanks. Each one runs as a CronJob.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MinItems=1
+	Feedings []Feeding `json:"feedings"`
+
+	// TimeZone is the time zone the cron schedules are interpreted in.
+	// Defaults to the time zone of the kube-controller-manager.
+	// +optional
+	TimeZone *string `json:"time_zone,omitempty"`
+
+	// GracePeriod is how late a feeding may start before it counts as missed.
+	// Defaults to 5m.
+	// +optional
+	GracePeriod *metav1.Duration `json:"grace_period,omitempty"`
+
+	// Suspend stops all feedings. Suspended feedings are not missed.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// Feeding is a meal served to every tank of an aquarium on a cron schedule.
+type Feeding struct {
+	// Name of the feeding, like breakfast.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=20
+	Name string `json:"name"`
+
+	// Schedule is the cron expression the feeding runs on, like "0 8 * * *".
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Food served, like flakes or brine-shrimp.
+	// +kubebuilder:validation:MinLength=1
+	Food string `json:"food"`
+
+	// PortionPerTank is the grams of food served to each tank, like 5 or 2.5.
+	PortionPerTank resource.Quantity `json:"portion_per_tank"`
+}
+
+// FeedingScheduleStatus defines the observed state of FeedingSchedule
+type FeedingScheduleStatus struct {
+	// Conditions are the Ready and MissedFeeding observations of the schedule.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the most recent generation reconciled by the operator.
+	// +optional
+	ObservedGeneration int64 `json:"observed_generation,omitempty"`
+
+	// ActiveSince is when the feedings were created or last resumed. Feedings
+	// due before then are not missed.
+	// +optional
+	ActiveSince *metav1.Time `json:"active_since,omitempty"`
+
+	// Feedings reports on every feeding of the schedule.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Feedings []FeedingStatus `json:"feedings,omitempty"`
+}
+
+// FeedingStatus reports when a feeding was last served and is served next.
+type FeedingStatus struct {
+	Name string `json:"name"`
+
+	// LastFeedingTime is when the feeding was last started.
+	// +optional
+	LastFeedingTime *metav1.Time `json:"last_feeding_time,omitempty"`
+
+	// LastSuccessfulFeedingTime is when the feeding last finished successfully.
+	// +optional
+	LastSuccessfulFeedingTime *metav1.Time `json:"last_successful_feeding_time,omitempty"`
+
+	// NextFeedingTime is when the feeding is served next.
+	// +optional
+	NextFeedingTime *metav1.Time `json:"next_feeding_time,omitempty"`
+
+	// MissedFeedings is the number of feedings that didn't start in time since
+	// the feeding was last served.
+	// +optional
+	MissedFeedings int32 `json:"missed_feedings,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Aquarium",type="string",JSONPath=".spec.aquarium",priority=0
+// +kubebuilder:printcolumn:name="Suspended",type="boolean",JSONPath=".spec.suspend",priority=1
+// +kubebuilder:printcolumn:name="Missed",type="string",JSONPath=".status.conditions[?(@.type==\"MissedFeeding\")].status",priority=0
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",priority=0
+
+// FeedingSchedule is the Schema for the feedingschedules API
+type FeedingSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FeedingScheduleSpec   `json:"spec,omitempty"`
+	Status FeedingScheduleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// FeedingScheduleList contains a list of FeedingSchedule
+type FeedingScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FeedingSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FeedingSchedule{}, &FeedingScheduleList{})
+}
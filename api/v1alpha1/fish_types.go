@@ -0,0 +1,86 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FishSpec defines the desired state of Fish
+type FishSpec struct {
+	// Aquarium is the name of the aquarium in the same namespace the fish live in.
+	// +kubebuilder:validation:MinLength=1
+	Aquarium string `json:"aquarium"`
+
+	// Species of the fish, like clownfish.
+	// +kubebuilder:validation:MinLength=1
+	Species string `json:"species"`
+
+	// Count is the number of fish of the species.
+	// +kubebuilder:validation:Minimum=1
+	Count int32 `json:"count"`
+
+	// Tank is the index of the tank the fish are placed in, counting from 0.
+	// The fish may be in any tank when it is left out.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Tank *int32 `json:"tank,omitempty"`
+}
+
+// FishStatus defines the observed state of Fish
+type FishStatus struct {
+	// Conditions are the Placed observations of the fish.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the most recent generation reconciled by the operator.
+	// +optional
+	ObservedGeneration int64 `json:"observed_generation,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Aquarium",type="string",JSONPath=".spec.aquarium",priority=0
+// +kubebuilder:printcolumn:name="Species",type="string",JSONPath=".spec.species",priority=0
+// +kubebuilder:printcolumn:name="Count",type="integer",JSONPath=".spec.count",priority=0
+// +kubebuilder:printcolumn:name="Tank",type="integer",JSONPath=".spec.tank",priority=1
+// +kubebuilder:printcolumn:name="Placed",type="string",JSONPath=".status.conditions[?(@.type==\"Placed\")].status",priority=0
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",priority=0
+
+// Fish is the Schema for the fish API
+type Fish struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FishSpec   `json:"spec,omitempty"`
+	Status FishStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// FishList contains a list of Fish
+type FishList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Fish `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Fish{}, &FishList{})
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LocationSpec defines where the tanks of the aquaria at a location are scheduled.
+type LocationSpec struct {
+	// NodeSelector must match a node's labels for tanks to be scheduled onto it.
+	// +optional
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+
+	// Affinity is the node, pod and pod anti affinity of the tanks.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Tolerations let the tanks be scheduled onto nodes with matching taints.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",priority=0
+
+// Location maps the location of an aquarium, like pier39, to the nodes its
+// tanks run on. The Location is looked up by the aquarium's spec.location,
+// lowercased and with characters a name can't hold replaced by dashes.
+type Location struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec LocationSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// LocationList contains a list of Location
+type LocationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Location `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Location{}, &LocationList{})
+}
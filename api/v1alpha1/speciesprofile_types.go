@@ -0,0 +1,97 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Temperament is how a species gets along with other species.
+// +kubebuilder:validation:Enum=Peaceful;SemiAggressive;Aggressive;Predator
+type Temperament string
+
+const (
+	Peaceful       Temperament = "Peaceful"
+	SemiAggressive Temperament = "SemiAggressive"
+	Aggressive     Temperament = "Aggressive"
+	Predator       Temperament = "Predator"
+)
+
+// WaterType is the kind of water a species lives in.
+// +kubebuilder:validation:Enum=Freshwater;Saltwater;Brackish
+type WaterType string
+
+const (
+	Freshwater WaterType = "Freshwater"
+	Saltwater  WaterType = "Saltwater"
+	Brackish   WaterType = "Brackish"
+)
+
+// SpeciesProfileSpec describes the needs of a species.
+type SpeciesProfileSpec struct {
+	// Temperament decides which species can share a tank. Predators eat
+	// Peaceful and SemiAggressive fish, Aggressive fish harass Peaceful ones.
+	Temperament Temperament `json:"temperament"`
+
+	// WaterType is the water the species lives in.
+	WaterType WaterType `json:"water_type"`
+
+	// Temperature is the range of water temperatures the species lives in.
+	Temperature TemperatureRange `json:"temperature"`
+
+	// MaxPerTank is the most fish of the species a single tank can hold.
+	// There is no limit when it is left out.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxPerTank *int32 `json:"max_per_tank,omitempty"`
+}
+
+// TemperatureRange is a range of water temperatures in degrees Celsius.
+// +kubebuilder:validation:XValidation:rule="self.min_celsius <= self.max_celsius",message="min_celsius must not be above max_celsius"
+type TemperatureRange struct {
+	MinCelsius int32 `json:"min_celsius"`
+	MaxCelsius int32 `json:"max_celsius"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Temperament",type="string",JSONPath=".spec.temperament",priority=0
+// +kubebuilder:printcolumn:name="Water",type="string",JSONPath=".spec.water_type",priority=0
+// +kubebuilder:printcolumn:name="Max Per Tank",type="integer",JSONPath=".spec.max_per_tank",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",priority=0
+
+// SpeciesProfile describes the needs of the species it is named after, which
+// decide what fish can share a tank.
+type SpeciesProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SpeciesProfileSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SpeciesProfileList contains a list of SpeciesProfile
+type SpeciesProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SpeciesProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SpeciesProfile{}, &SpeciesProfileList{})
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WaterReadingSpec defines the desired state of WaterReading
+type WaterReadingSpec struct {
+	// Aquarium is the name of the aquarium in the same namespace the reading was taken in.
+	// +kubebuilder:validation:MinLength=1
+	Aquarium string `json:"aquarium"`
+
+	// Tank is the index of the tank the reading was taken in.
+	// +kubebuilder:validation:Minimum=0
+	Tank int32 `json:"tank"`
+
+	// TakenAt is when the reading was taken. Defaults to when it was created.
+	// +optional
+	TakenAt *metav1.Time `json:"taken_at,omitempty"`
+
+	// PH is the pH of the water, like 7.2.
+	// +optional
+	PH *resource.Quantity `json:"ph,omitempty"`
+
+	// TemperatureCelsius is the water temperature in degrees Celsius, like 25.5.
+	// +optional
+	TemperatureCelsius *resource.Quantity `json:"temperature_celsius,omitempty"`
+
+	// AmmoniaPPM is the ammonia concentration in parts per million, like 0.25.
+	// +optional
+	AmmoniaPPM *resource.Quantity `json:"ammonia_ppm,omitempty"`
+
+	// NitratePPM is the nitrate concentration in parts per million, like 20.
+	// +optional
+	NitratePPM *resource.Quantity `json:"nitrate_ppm,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Aquarium",type="string",JSONPath=".spec.aquarium",priority=0
+// +kubebuilder:printcolumn:name="Tank",type="integer",JSONPath=".spec.tank",priority=0
+// +kubebuilder:printcolumn:name="pH",type="string",JSONPath=".spec.ph",priority=0
+// +kubebuilder:printcolumn:name="Temperature",type="string",JSONPath=".spec.temperature_celsius",priority=0
+// +kubebuilder:printcolumn:name="Ammonia",type="string",JSONPath=".spec.ammonia_ppm",priority=1
+// +kubebuilder:printcolumn:name="Nitrate",type="string",JSONPath=".spec.nitrate_ppm",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",priority=0
+
+// WaterReading is the Schema for the waterreadings API. Readings are
+// measurements reported by the tanks' probes and are never changed after
+// they are taken.
+type WaterReading struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec WaterReadingSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// WaterReadingList contains a list of WaterReading
+type WaterReadingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WaterReading `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WaterReading{}, &WaterReadingList{})
+}
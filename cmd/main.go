@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 
@@ -28,11 +29,15 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+	"github.com/tydanny/aquarium-operator/internal/config"
 	"github.com/tydanny/aquarium-operator/internal/controller"
+	webhookfunv1alpha1 "github.com/tydanny/aquarium-operator/internal/webhook/v1alpha1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -49,14 +54,7 @@ func init() {
 }
 
 func main() {
-	var metricsAddr string
-	var enableLeaderElection bool
-	var probeAddr string
-	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
-	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
-		"Enable leader election for controller manager. "+
-			"Enabling this will ensure there is only one active controller manager.")
+	flags := config.BindFlags(flag.CommandLine)
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,13 +63,22 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	cfg, err := flags.Complete()
+	if err != nil {
+		setupLog.Error(err, "unable to load configuration")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "1b971594.tydanny.com",
+		Scheme:                  scheme,
+		MetricsBindAddress:      cfg.Metrics.BindAddress,
+		Port:                    cfg.Webhook.Port,
+		HealthProbeBindAddress:  cfg.Health.BindAddress,
+		LeaderElection:          cfg.LeaderElection.Enabled,
+		LeaderElectionID:        cfg.LeaderElection.ID,
+		LeaderElectionNamespace: cfg.LeaderElection.Namespace,
+		Cache:                   cache.Options{Namespaces: cfg.Namespaces, ByObject: controller.CacheByObject()},
+		Controller:              ctrlconfig.Controller{MaxConcurrentReconciles: cfg.MaxConcurrentReconciles},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -89,13 +96,57 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = controller.SetupFieldIndexes(context.Background(), mgr); err != nil {
+		setupLog.Error(err, "unable to set up field indexes")
+		os.Exit(1)
+	}
 	if err = (&controller.AquariumReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("aquarium-controller"),
+
+		APIReader:                 mgr.GetAPIReader(),
+		DefaultTankImage:          cfg.DefaultTankImage,
+		DeploymentDeletionRequeue: cfg.Requeue.DeploymentDeletion.Duration,
+		DrainRequeue:              cfg.Requeue.Drain.Duration,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Aquarium")
+		os.Exit(1)
+	}
+	if err = (&controller.FishReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Aquarium")
+		setupLog.Error(err, "unable to create controller", "controller", "Fish")
 		os.Exit(1)
 	}
+	if err = (&controller.FeedingScheduleReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "FeedingSchedule")
+		os.Exit(1)
+	}
+	if err = (&controller.ExhibitReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Exhibit")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookfunv1alpha1.SetupAquariumWebhookWithManager(mgr, &webhookfunv1alpha1.AquariumCustomValidator{
+			AllowedLocations:     cfg.AllowedLocations,
+			MaxTanksPerNamespace: cfg.MaxTanksPerNamespace,
+		}); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Aquarium")
+			os.Exit(1)
+		}
+		if err = webhookfunv1alpha1.SetupFishWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Fish")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,218 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads the configuration file of the aquarium operator.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+	"github.com/tydanny/aquarium-operator/internal/controller"
+)
+
+// The version and kind of the configuration file.
+const (
+	APIVersion = "config.fun.tydanny.com/v1alpha1"
+	Kind       = "OperatorConfig"
+)
+
+// OperatorConfig configures the manager and controllers of the operator.
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Metrics configures the metrics endpoint.
+	Metrics EndpointConfig `json:"metrics"`
+
+	// Health configures the health probe endpoint.
+	Health EndpointConfig `json:"health"`
+
+	// Webhook configures the webhook server.
+	Webhook WebhookConfig `json:"webhook"`
+
+	// LeaderElection configures leader election between replicas of the operator.
+	LeaderElection LeaderElectionConfig `json:"leader_election"`
+
+	// Namespaces limits the operator to the given namespaces. Every namespace
+	// is watched when empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// MaxConcurrentReconciles is the number of objects of a kind reconciled at once.
+	MaxConcurrentReconciles int `json:"max_concurrent_reconciles"`
+
+	// DefaultTankImage is run by tanks whose template has no image.
+	DefaultTankImage string `json:"default_tank_image"`
+
+	// Requeue configures how long the controllers wait before checking again
+	// on work in progress.
+	Requeue RequeueConfig `json:"requeue"`
+
+	// AllowedLocations are the locations aquaria may be built at. Any
+	// location is allowed when empty.
+	AllowedLocations []string `json:"allowed_locations,omitempty"`
+
+	// MaxTanksPerNamespace is the maximum number of tanks across all aquaria
+	// of a namespace. Zero means no limit.
+	MaxTanksPerNamespace int32 `json:"max_tanks_per_namespace"`
+}
+
+// EndpointConfig configures an endpoint served by the operator.
+type EndpointConfig struct {
+	// BindAddress is the address the endpoint binds to. "0" disables it.
+	BindAddress string `json:"bind_address"`
+}
+
+// WebhookConfig configures the webhook server.
+type WebhookConfig struct {
+	// Port the webhook server serves on.
+	Port int `json:"port"`
+}
+
+// LeaderElectionConfig configures leader election.
+type LeaderElectionConfig struct {
+	// Enabled ensures there is only one active controller manager.
+	Enabled bool `json:"enabled"`
+
+	// ID names the lock held by the leader.
+	ID string `json:"id"`
+
+	// Namespace the lock is held in. Defaults to the namespace the operator runs in.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// RequeueConfig configures requeue intervals.
+type RequeueConfig struct {
+	// DeploymentDeletion is how long to wait before checking again on a
+	// Deployment that is being deleted.
+	DeploymentDeletion metav1.Duration `json:"deployment_deletion"`
+
+	// Drain is how long to wait before checking again on tanks being drained
+	// by the teardown of an aquarium.
+	Drain metav1.Duration `json:"drain"`
+}
+
+// Default returns the configuration the operator runs with when no
+// configuration file is given.
+func Default() *OperatorConfig {
+	return &OperatorConfig{
+		TypeMeta:                metav1.TypeMeta{APIVersion: APIVersion, Kind: Kind},
+		Metrics:                 EndpointConfig{BindAddress: ":8080"},
+		Health:                  EndpointConfig{BindAddress: ":8081"},
+		Webhook:                 WebhookConfig{Port: 9443},
+		LeaderElection:          LeaderElectionConfig{ID: "1b971594.tydanny.com"},
+		MaxConcurrentReconciles: 1,
+		DefaultTankImage:        funv1alpha1.DefaultTankImage,
+		Requeue: RequeueConfig{
+			DeploymentDeletion: metav1.Duration{Duration: controller.DefaultDeploymentDeletionRequeue},
+			Drain:              metav1.Duration{Duration: controller.DefaultDrainRequeue},
+		},
+	}
+}
+
+// Load reads a configuration file. Settings missing from the file keep their
+// defaults, and unknown settings are rejected. The result is not validated.
+func Load(path string) (*OperatorConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := Default()
+	cfg.TypeMeta = metav1.TypeMeta{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if cfg.APIVersion != APIVersion || cfg.Kind != Kind {
+		return nil, fmt.Errorf("config file %s is a %s %s, expected a %s %s",
+			path, cfg.APIVersion, cfg.Kind, APIVersion, Kind)
+	}
+
+	return cfg, nil
+}
+
+// Validate reports every setting the operator can't run with.
+func (c *OperatorConfig) Validate() error {
+	var errs []error
+
+	if err := validateBindAddress(c.Metrics.BindAddress); err != nil {
+		errs = append(errs, fmt.Errorf("metrics.bind_address: %w", err))
+	}
+	if err := validateBindAddress(c.Health.BindAddress); err != nil {
+		errs = append(errs, fmt.Errorf("health.bind_address: %w", err))
+	}
+
+	if c.Webhook.Port < 1 || c.Webhook.Port > 65535 {
+		errs = append(errs, fmt.Errorf("webhook.port: %d is not a valid port", c.Webhook.Port))
+	}
+
+	if c.LeaderElection.Enabled && c.LeaderElection.ID == "" {
+		errs = append(errs, errors.New("leader_election.id: required when leader election is enabled"))
+	}
+	if ns := c.LeaderElection.Namespace; ns != "" {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, fmt.Errorf("leader_election.namespace: %s", msg))
+		}
+	}
+
+	for _, ns := range c.Namespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, fmt.Errorf("namespaces: %q: %s", ns, msg))
+		}
+	}
+
+	if c.MaxConcurrentReconciles < 1 {
+		errs = append(errs, fmt.Errorf("max_concurrent_reconciles: must be at least 1, got %d",
+			c.MaxConcurrentReconciles))
+	}
+
+	if c.DefaultTankImage == "" {
+		errs = append(errs, errors.New("default_tank_image: required"))
+	}
+
+	if c.Requeue.DeploymentDeletion.Duration <= 0 {
+		errs = append(errs, errors.New("requeue.deployment_deletion: must be positive"))
+	}
+	if c.Requeue.Drain.Duration <= 0 {
+		errs = append(errs, errors.New("requeue.drain: must be positive"))
+	}
+
+	if c.MaxTanksPerNamespace < 0 {
+		errs = append(errs, errors.New("max_tanks_per_namespace: must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateBindAddress accepts a host:port address, or "0" to disable the endpoint.
+func validateBindAddress(address string) error {
+	if address == "0" {
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/tydanny/aquarium-operator/internal/config"
+)
+
+var _ = Describe("Config", func() {
+	writeConfig := func(content string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+		return path
+	}
+
+	Context("When loading a configuration file", func() {
+		It("should keep the defaults of missing settings", func() {
+			cfg, err := config.Load(writeConfig(`
+apiVersion: config.fun.tydanny.com/v1alpha1
+kind: OperatorConfig
+namespaces: [reef, lagoon]
+max_concurrent_reconciles: 4
+requeue:
+  drain: 10s
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Validate()).To(Succeed())
+			Expect(cfg.Namespaces).To(Equal([]string{"reef", "lagoon"}))
+			Expect(cfg.MaxConcurrentReconciles).To(Equal(4))
+			Expect(cfg.Requeue.Drain.Duration).To(Equal(10 * time.Second))
+			Expect(cfg.Requeue.DeploymentDeletion).To(Equal(config.Default().Requeue.DeploymentDeletion))
+			Expect(cfg.Webhook.Port).To(Equal(9443))
+		})
+
+		It("should reject files of another version", func() {
+			_, err := config.Load(writeConfig(`
+apiVersion: config.fun.tydanny.com/v2
+kind: OperatorConfig
+`))
+			Expect(err).To(MatchError(ContainSubstring("expected a config.fun.tydanny.com/v1alpha1 OperatorConfig")))
+		})
+
+		It("should reject unknown settings", func() {
+			_, err := config.Load(writeConfig(`
+apiVersion: config.fun.tydanny.com/v1alpha1
+kind: OperatorConfig
+max_tanks: 3
+`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When validating a configuration", func() {
+		It("should report every invalid setting", func() {
+			cfg := config.Default()
+			cfg.Metrics.BindAddress = "8080"
+			cfg.Webhook.Port = 0
+			cfg.Namespaces = []string{"Reef"}
+			cfg.MaxConcurrentReconciles = 0
+			cfg.Requeue.Drain.Duration = 0
+
+			err := cfg.Validate()
+			Expect(err).To(MatchError(ContainSubstring("metrics.bind_address")))
+			Expect(err).To(MatchError(ContainSubstring("webhook.port")))
+			Expect(err).To(MatchError(ContainSubstring("namespaces")))
+			Expect(err).To(MatchError(ContainSubstring("max_concurrent_reconciles")))
+			Expect(err).To(MatchError(ContainSubstring("requeue.drain")))
+		})
+	})
+
+	Context("When flags are set", func() {
+		It("should override the configuration file", func() {
+			path := writeConfig(`
+apiVersion: config.fun.tydanny.com/v1alpha1
+kind: OperatorConfig
+default_tank_image: example.com/tank:v1
+max_concurrent_reconciles: 2
+`)
+
+			fs := flag.NewFlagSet("operator", flag.ContinueOnError)
+			flags := config.BindFlags(fs)
+			Expect(fs.Parse([]string{
+				"--config=" + path,
+				"--max-concurrent-reconciles=8",
+				"--allowed-locations=Atlanta, Boston",
+			})).To(Succeed())
+
+			cfg, err := flags.Complete()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.DefaultTankImage).To(Equal("example.com/tank:v1"))
+			Expect(cfg.MaxConcurrentReconciles).To(Equal(8))
+			Expect(cfg.AllowedLocations).To(Equal([]string{"Atlanta", "Boston"}))
+		})
+
+		It("should refuse invalid overrides", func() {
+			fs := flag.NewFlagSet("operator", flag.ContinueOnError)
+			flags := config.BindFlags(fs)
+			Expect(fs.Parse([]string{"--drain-requeue=-1s"})).To(Succeed())
+
+			_, err := flags.Complete()
+			Expect(err).To(MatchError(ContainSubstring("requeue.drain")))
+		})
+	})
+})
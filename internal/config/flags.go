@@ -0,0 +1,131 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Flags are the command line flags of the operator. --config names the
+// configuration file, and every other flag that is set overrides the setting
+// of the file.
+type Flags struct {
+	file             string
+	flagged          OperatorConfig
+	namespaces       string
+	allowedLocations string
+	maxTanks         int
+	set              map[string]func(cfg *OperatorConfig)
+	fs               *flag.FlagSet
+}
+
+// BindFlags registers the flags of the operator on a flag set.
+func BindFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{flagged: *Default(), fs: fs}
+	c := &f.flagged
+
+	fs.StringVar(&f.file, "config", "",
+		"The configuration file of the operator. Flags that are set override its settings.")
+	fs.StringVar(&c.Metrics.BindAddress, "metrics-bind-address", c.Metrics.BindAddress,
+		"The address the metric endpoint binds to.")
+	fs.StringVar(&c.Health.BindAddress, "health-probe-bind-address", c.Health.BindAddress,
+		"The address the probe endpoint binds to.")
+	fs.IntVar(&c.Webhook.Port, "webhook-port", c.Webhook.Port, "The port the webhook server serves on.")
+	fs.BoolVar(&c.LeaderElection.Enabled, "leader-elect", c.LeaderElection.Enabled,
+		"Enable leader election for controller manager. "+
+			"Enabling this will ensure there is only one active controller manager.")
+	fs.StringVar(&c.LeaderElection.ID, "leader-election-id", c.LeaderElection.ID,
+		"The name of the lock held by the leader.")
+	fs.StringVar(&c.LeaderElection.Namespace, "leader-election-namespace", c.LeaderElection.Namespace,
+		"The namespace the leader lock is held in. Defaults to the namespace the operator runs in.")
+	fs.StringVar(&f.namespaces, "namespaces", "",
+		"Comma separated list of namespaces to watch. Every namespace is watched when empty.")
+	fs.IntVar(&c.MaxConcurrentReconciles, "max-concurrent-reconciles", c.MaxConcurrentReconciles,
+		"The number of objects of a kind reconciled at once.")
+	fs.StringVar(&c.DefaultTankImage, "default-tank-image", c.DefaultTankImage,
+		"The image run by tanks whose template has no image.")
+	fs.DurationVar(&c.Requeue.DeploymentDeletion.Duration, "deployment-deletion-requeue",
+		c.Requeue.DeploymentDeletion.Duration,
+		"How long to wait before checking again on a deployment that is being deleted.")
+	fs.DurationVar(&c.Requeue.Drain.Duration, "drain-requeue", c.Requeue.Drain.Duration,
+		"How long to wait before checking again on tanks being drained.")
+	fs.StringVar(&f.allowedLocations, "allowed-locations", "",
+		"Comma separated list of locations aquaria may be built at. Any location is allowed when empty.")
+	fs.IntVar(&f.maxTanks, "max-tanks-per-namespace", 0,
+		"The maximum number of tanks across all aquaria of a namespace. Zero means no limit.")
+
+	f.set = map[string]func(cfg *OperatorConfig){
+		"metrics-bind-address":      func(cfg *OperatorConfig) { cfg.Metrics = c.Metrics },
+		"health-probe-bind-address": func(cfg *OperatorConfig) { cfg.Health = c.Health },
+		"webhook-port":              func(cfg *OperatorConfig) { cfg.Webhook = c.Webhook },
+		"leader-elect":              func(cfg *OperatorConfig) { cfg.LeaderElection.Enabled = c.LeaderElection.Enabled },
+		"leader-election-id":        func(cfg *OperatorConfig) { cfg.LeaderElection.ID = c.LeaderElection.ID },
+		"leader-election-namespace": func(cfg *OperatorConfig) {
+			cfg.LeaderElection.Namespace = c.LeaderElection.Namespace
+		},
+		"namespaces":                func(cfg *OperatorConfig) { cfg.Namespaces = splitList(f.namespaces) },
+		"max-concurrent-reconciles": func(cfg *OperatorConfig) { cfg.MaxConcurrentReconciles = c.MaxConcurrentReconciles },
+		"default-tank-image":        func(cfg *OperatorConfig) { cfg.DefaultTankImage = c.DefaultTankImage },
+		"deployment-deletion-requeue": func(cfg *OperatorConfig) {
+			cfg.Requeue.DeploymentDeletion = c.Requeue.DeploymentDeletion
+		},
+		"drain-requeue":           func(cfg *OperatorConfig) { cfg.Requeue.Drain = c.Requeue.Drain },
+		"allowed-locations":       func(cfg *OperatorConfig) { cfg.AllowedLocations = splitList(f.allowedLocations) },
+		"max-tanks-per-namespace": func(cfg *OperatorConfig) { cfg.MaxTanksPerNamespace = int32(f.maxTanks) },
+	}
+
+	return f
+}
+
+// Complete loads the configuration file, when there is one, overrides it
+// with the flags that are set and validates the result. It is called once
+// the flags are parsed.
+func (f *Flags) Complete() (*OperatorConfig, error) {
+	cfg := Default()
+	if f.file != "" {
+		var err error
+		if cfg, err = Load(f.file); err != nil {
+			return nil, err
+		}
+	}
+
+	f.fs.Visit(func(fl *flag.Flag) {
+		if override, ok := f.set[fl.Name]; ok {
+			override(cfg)
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// splitList splits a comma separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -18,33 +18,83 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
-	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
 )
 
+// DefaultDeploymentDeletionRequeue is how long to wait before checking again
+// on a Deployment that is being deleted, unless configured otherwise.
+const DefaultDeploymentDeletionRequeue = 2 * time.Second
+
 // AquariumReconciler reconciles a Aquarium object
 type AquariumReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// APIReader, when set, lists the pods of legacy tanks, which the cache
+	// limited by CacheByObject doesn't hold. The client is used when it is nil.
+	APIReader client.Reader
+
+	// Reservations, when set, is called to release external reservations
+	// of an aquarium as it is torn down.
+	Reservations ReservationReleaser
+
+	// DefaultTankImage is run by tanks whose template has no image. Tanks run
+	// funv1alpha1.DefaultTankImage when it is empty.
+	DefaultTankImage string
+
+	// DeploymentDeletionRequeue and DrainRequeue are how long to wait before
+	// checking again on a Deployment being deleted and on draining tanks.
+	// They default to DefaultDeploymentDeletionRequeue and DefaultDrainRequeue.
+	DeploymentDeletionRequeue time.Duration
+	DrainRequeue              time.Duration
+
+	events  *eventDeduper
+	metrics *fleetMetrics
 }
 
 // +kubebuilder:rbac:groups=fun.tydanny.com,resources=aquaria,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=fun.tydanny.com,resources=aquaria/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=fun.tydanny.com,resources=aquaria/finalizers,verbs=update
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=locations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=fish,verbs=get;list;watch
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=speciesprofiles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=feedingschedules,verbs=get;list;watch
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=waterreadings,verbs=get;list;watch;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
 
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.15.0/pkg/reconcile
@@ -54,123 +104,432 @@ func (r *AquariumReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	// Get our Aquarium CR
 	var aquarium funv1alpha1.Aquarium
 	if err := r.Get(ctx, req.NamespacedName, &aquarium); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.metrics.forget(req.Namespace, req.Name)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// Tear the aquarium down if it is being deleted
+	if !aquarium.DeletionTimestamp.IsZero() {
+		return r.finalize(ctx, &aquarium)
+	}
+
+	if !controllerutil.ContainsFinalizer(&aquarium, AquariumFinalizer) {
+		controllerutil.AddFinalizer(&aquarium, AquariumFinalizer)
+		if err := r.Update(ctx, &aquarium); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Drive the tanks towards the desired state
+	now := time.Now()
+	maintenance := evaluateMaintenance(&aquarium, now)
+	schedule := evaluateSchedule(&aquarium, now)
+	replicas := desiredReplicas(&aquarium, maintenance, schedule)
+	liveDeploy, result, reconcileErr := r.reconcileWorkload(ctx, &aquarium, maintenance, replicas)
+	if schedule.Err != nil && reconcileErr == nil {
+		reconcileErr = schedule.Err
+	}
+	if err := r.reconcileDisruptionBudget(ctx, &aquarium, effectiveTanks(replicas, liveDeploy)); err != nil &&
+		reconcileErr == nil {
+		reconcileErr = err
+	}
+	if err := r.setSchedulableCondition(ctx, &aquarium); err != nil && reconcileErr == nil {
+		reconcileErr = err
+	}
+	if err := r.reconcileFish(ctx, &aquarium); err != nil && reconcileErr == nil {
+		reconcileErr = err
+	}
+	if err := r.setMissedFeedingCondition(ctx, &aquarium); err != nil && reconcileErr == nil {
+		reconcileErr = err
+	}
+	if err := r.reconcileTanks(ctx, &aquarium); err != nil && reconcileErr == nil {
+		reconcileErr = err
+	}
+	url, err := r.reconcileExposure(ctx, &aquarium)
+	if err != nil && reconcileErr == nil {
+		reconcileErr = err
+	}
+	water, err := r.reconcileWaterQuality(ctx, &aquarium)
+	if err != nil && reconcileErr == nil {
+		reconcileErr = err
+	}
+
+	// Update Aquarium status
+	report := applyMaintenance(applyWaterQuality(evaluateHealth(&aquarium, liveDeploy), water), maintenance)
+	previousHealth := aquarium.Status.FishHealth
+	r.recordHealthTransition(&aquarium, report)
+	aquarium.Status.FishHealth = report.Health
+	aquarium.Status.NumTanksReady = 0
+	if liveDeploy != nil {
+		aquarium.Status.NumTanksReady = liveDeploy.Status.AvailableReplicas
+	}
+	if reconcileErr == nil {
+		aquarium.Status.ObservedGeneration = aquarium.Generation
+	}
+	aquarium.Status.Selector = labels.SelectorFromSet(selectorLabels(&aquarium)).String()
+	aquarium.Status.EffectiveTanks = effectiveTanks(replicas, liveDeploy)
+	aquarium.Status.ActiveSchedule = schedule.Entry
+	aquarium.Status.URL = url
+	setStatusConditions(&aquarium, liveDeploy, report, reconcileErr)
+	r.setMaintenanceCondition(&aquarium, maintenance)
+	r.metrics.record(&aquarium, liveDeploy, previousHealth, report)
+
+	if err := r.applyStatus(ctx, &aquarium); err != nil {
+		// Conflicts are expected while others write the aquarium too, try again.
+		if apierrors.IsConflict(err) {
+			log.V(1).Info("conflict applying aquarium status, requeuing", "error", err.Error())
+			return ctrl.Result{Requeue: true}, nil
+		}
+
+		log.Error(err, "failed to apply aquarium status")
+		r.events.Eventf(&aquarium, corev1.EventTypeWarning, StatusUpdateFailed,
+			"Failed to update the aquarium status: %v", err)
+		if reconcileErr == nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Judge the water again once the oldest reading leaves the window.
+	if water.Expires != nil {
+		result = requeueBefore(result, time.Until(*water.Expires))
+	}
+	// Scale the tanks when a maintenance window opens or closes, or the
+	// schedule moves on to another entry.
+	if after := maintenance.requeueAfter(time.Now()); after != 0 {
+		result = requeueBefore(result, after)
+	}
+	if !schedule.Next.IsZero() {
+		result = requeueBefore(result, time.Until(schedule.Next))
+	}
+
+	return result, reconcileErr
+}
+
+// requeueBefore makes sure a result is requeued no later than after.
+func requeueBefore(result ctrl.Result, after time.Duration) ctrl.Result {
+	if after <= 0 {
+		after = time.Second
+	}
+	if result.RequeueAfter == 0 || after < result.RequeueAfter {
+		result.RequeueAfter = after
+	}
+
+	return result
+}
+
+// reconcileDeployment applies the desired Deployment of an aquarium. It returns
+// the Deployment the tanks should be judged by: the applied one, or the live
+// one if it could not be applied, together with the canary tanks. It is nil
+// when the aquarium has no Deployment. Paused aquaria are only observed. The
+// replicas are left alone when nil.
+func (r *AquariumReconciler) reconcileDeployment(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	maintenance maintenanceState,
+	replicas *int32,
+) (*appsv1.Deployment, ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
 	// Gather the state of the world
 	var aquariumDeploy appsv1.Deployment
-	if err := r.Get(ctx, req.NamespacedName, &aquariumDeploy); client.IgnoreNotFound(err) != nil {
-		return ctrl.Result{}, err
+	err := r.Get(ctx, client.ObjectKeyFromObject(aquarium), &aquariumDeploy)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, ctrl.Result{}, err
 	}
 
-	// Update Aquarium status
-	aquarium.Status = funv1alpha1.AquariumStatus{
-		Conditions:    []metav1.Condition{},
-		NumTanksReady: aquariumDeploy.Status.AvailableReplicas,
-		FishHealth:    funv1alpha1.Unknown,
+	var liveDeploy *appsv1.Deployment
+	if err == nil {
+		// Deployments the aquarium doesn't run its tanks in are left alone.
+		if !isTanksDeployment(&aquariumDeploy, aquarium) {
+			return nil, ctrl.Result{}, fmt.Errorf(
+				"deployment %s already exists and is not owned by the aquarium", aquariumDeploy.Name)
+		}
+		liveDeploy = &aquariumDeploy
 	}
 
-	if aquariumDeploy.Status.ReadyReplicas == aquarium.Spec.NumTanks {
-		setUnHealthyCondition(&aquarium)
-		aquarium.Status.FishHealth = funv1alpha1.Healthy
-	} else {
-		setHealthyCondition(&aquarium)
-		aquarium.Status.FishHealth = funv1alpha1.Unhealthy
+	if maintenance.Paused {
+		log.V(1).Info("aquarium is paused, leaving the tanks alone")
+		return liveDeploy, ctrl.Result{}, nil
 	}
 
-	// If we fail to update status don't requeue for reconcile.
-	// We do this so that we can get to the desired state step.
-	if err := r.Status().Update(ctx, &aquarium); err != nil {
-		log.Error(err, "failed to update aquarium status")
+	if liveDeploy != nil {
+		requeue := orDefault(r.DeploymentDeletionRequeue, DefaultDeploymentDeletionRequeue)
+
+		// A Deployment that is still being deleted can't be replaced yet.
+		if !liveDeploy.DeletionTimestamp.IsZero() {
+			log.Info("waiting for deployment deletion to finish")
+			return liveDeploy, ctrl.Result{RequeueAfter: requeue}, nil
+		}
+
+		migrated, err := r.migrateDeployment(ctx, aquarium, liveDeploy)
+		if err != nil {
+			return liveDeploy, ctrl.Result{}, err
+		}
+		if migrated {
+			return liveDeploy, ctrl.Result{RequeueAfter: requeue}, nil
+		}
 	}
 
-	desiredDeploy := newDeployment(&aquarium)
+	location, err := r.getLocation(ctx, aquarium)
+	if err != nil {
+		return liveDeploy, ctrl.Result{}, err
+	}
+
+	desiredDeploy := newDeployment(aquarium, location, replicas, r.DefaultTankImage)
+	if err := setTemplateHash(desiredDeploy); err != nil {
+		return liveDeploy, ctrl.Result{}, err
+	}
+	canaryDeploy := splitCanary(aquarium, liveDeploy, desiredDeploy)
+	if err := setDesiredState(desiredDeploy); err != nil {
+		return liveDeploy, ctrl.Result{}, err
+	}
+
+	apply, err := r.reconcileDrift(ctx, aquarium, liveDeploy, desiredDeploy)
+	if err != nil {
+		return liveDeploy, ctrl.Result{}, err
+	}
 
 	// Apply the desired deployment using server side apply
-	if err := r.Patch(
-		ctx,
-		desiredDeploy,
-		client.Apply,
-		client.ForceOwnership,
-		client.FieldOwner(AquariumOperator),
-	); err != nil {
-		return ctrl.Result{}, err
+	judgedDeploy := liveDeploy
+	if apply {
+		if err := r.clearRollingUpdate(ctx, liveDeploy, desiredDeploy); err != nil {
+			return liveDeploy, ctrl.Result{}, err
+		}
+		if err := r.handOverReplicas(ctx, liveDeploy, desiredDeploy); err != nil {
+			return liveDeploy, ctrl.Result{}, err
+		}
+		if err := r.Patch(
+			ctx,
+			desiredDeploy,
+			client.Apply,
+			client.ForceOwnership,
+			client.FieldOwner(AquariumOperator),
+		); err != nil {
+			r.events.Eventf(aquarium, corev1.EventTypeWarning, ApplyFailed, "Failed to apply deployment: %v", err)
+			return liveDeploy, ctrl.Result{}, fmt.Errorf("failed to apply deployment: %w", err)
+		}
+		r.recordDeploymentChanges(aquarium, liveDeploy, desiredDeploy)
+		judgedDeploy = desiredDeploy
 	}
 
-	return ctrl.Result{}, nil
+	canaryDeploy, err = r.reconcileCanary(ctx, aquarium, canaryDeploy)
+	if err != nil {
+		return judgedDeploy, ctrl.Result{}, err
+	}
+	aquarium.Status.Rollout = rolloutStatus(judgedDeploy, canaryDeploy)
+	judgedDeploy = withCanary(judgedDeploy, canaryDeploy)
+
+	if err := r.reconcileAutoscaler(ctx, aquarium, maintenance); err != nil {
+		return judgedDeploy, ctrl.Result{}, err
+	}
+
+	if err := r.cleanupLegacyReplicaSets(ctx, aquarium, judgedDeploy); err != nil {
+		return judgedDeploy, ctrl.Result{}, err
+	}
+
+	headless := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: headlessServiceName(aquarium)}}
+	if err := r.retireWorkloads(ctx, aquarium, judgedDeploy, &appsv1.StatefulSet{}, headless); err != nil {
+		return judgedDeploy, ctrl.Result{}, err
+	}
+
+	return judgedDeploy, ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AquariumReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&funv1alpha1.Aquarium{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+	r.events = newEventDeduper(r.Recorder, eventDedupWindow)
+	r.metrics = newFleetMetrics()
+
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		For(&funv1alpha1.Aquarium{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+		))).
 		Owns(&appsv1.Deployment{}, builder.WithPredicates(AquariumLabelPredicate)).
-		Complete(r)
+		Owns(&appsv1.StatefulSet{}, builder.WithPredicates(AquariumLabelPredicate)).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}, builder.WithPredicates(AquariumLabelPredicate)).
+		Owns(&policyv1.PodDisruptionBudget{}, builder.WithPredicates(AquariumLabelPredicate)).
+		Owns(&corev1.Service{}, builder.WithPredicates(AquariumLabelPredicate)).
+		Owns(&networkingv1.Ingress{}, builder.WithPredicates(AquariumLabelPredicate)).
+		Watches(&funv1alpha1.Location{}, handler.EnqueueRequestsFromMapFunc(r.aquariaAtLocation)).
+		Watches(
+			&funv1alpha1.Fish{},
+			enqueueReferencedAquarium(fishAquarium),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(&funv1alpha1.SpeciesProfile{}, handler.EnqueueRequestsFromMapFunc(r.aquariaWithSpecies)).
+		Watches(&funv1alpha1.FeedingSchedule{}, enqueueReferencedAquarium(feedingScheduleAquarium)).
+		Watches(
+			&funv1alpha1.WaterReading{},
+			enqueueReferencedAquarium(waterReadingAquarium),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(
+			&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(tankAquarium),
+			builder.WithPredicates(AquariumLabelPredicate, TankStatusChangedPredicate),
+		).
+		Watches(
+			&corev1.Node{},
+			handler.EnqueueRequestsFromMapFunc(r.aquariaAtMappedLocations),
+			builder.WithPredicates(NodeSchedulingChangedPredicate),
+		)
+
+	// HTTPRoutes can only be watched in clusters that serve the Gateway API.
+	if _, err := mgr.GetRESTMapper().RESTMapping(httpRouteGVK.GroupKind(), httpRouteGVK.Version); err == nil {
+		bldr = bldr.Owns(emptyHTTPRoute(), builder.WithPredicates(AquariumLabelPredicate))
+	}
+
+	return bldr.Complete(r)
 }
 
-func setHealthyCondition(aquarium *funv1alpha1.Aquarium) {
-	apimeta.SetStatusCondition(&aquarium.Status.Conditions, metav1.Condition{
-		Type:               AquariumReady,
-		Status:             metav1.ConditionTrue,
-		ObservedGeneration: aquarium.Generation,
-		Reason:             AquariumIsHealthy,
-		Message:            "The aquarium is ready!",
-	})
+// recordHealthTransition emits an event when the fish health of an aquarium changes.
+func (r *AquariumReconciler) recordHealthTransition(aquarium *funv1alpha1.Aquarium, report healthReport) {
+	previous := aquarium.Status.FishHealth
+	if previous == "" || previous == report.Health {
+		return
+	}
+
+	eventType := corev1.EventTypeWarning
+	if report.Health == funv1alpha1.Healthy {
+		eventType = corev1.EventTypeNormal
+	}
+
+	r.events.Eventf(aquarium, eventType, report.Reason,
+		"Fish health changed from %s to %s: %s", previous, report.Health, report.Message)
 }
 
-func setUnHealthyCondition(aquarium *funv1alpha1.Aquarium) {
-	apimeta.SetStatusCondition(&aquarium.Status.Conditions, metav1.Condition{
-		Type:               AquariumReady,
-		Status:             metav1.ConditionFalse,
-		ObservedGeneration: aquarium.Generation,
-		Reason:             AquariumIsUnHealthy,
-		Message:            "The aquarium is not ready :(",
-	})
+// recordDeploymentChanges emits events for a Deployment that was just created or scaled.
+func (r *AquariumReconciler) recordDeploymentChanges(
+	aquarium *funv1alpha1.Aquarium,
+	liveDeploy, appliedDeploy *appsv1.Deployment,
+) {
+	if liveDeploy == nil {
+		r.events.Eventf(aquarium, corev1.EventTypeNormal, DeploymentCreated,
+			"Created deployment %s", appliedDeploy.Name)
+		return
+	}
+
+	if liveDeploy.Spec.Replicas != nil && appliedDeploy.Spec.Replicas != nil &&
+		*liveDeploy.Spec.Replicas != *appliedDeploy.Spec.Replicas {
+		r.events.Eventf(aquarium, corev1.EventTypeNormal, Scaled,
+			"Scaled tanks from %d to %d", *liveDeploy.Spec.Replicas, *appliedDeploy.Spec.Replicas)
+	}
 }
 
-func newDeployment(aquarium *funv1alpha1.Aquarium) *appsv1.Deployment {
-	return &appsv1.Deployment{
+// newDeployment builds the Deployment running the tanks of an aquarium, rolled
+// out with its rollout strategy. The replicas are left out when they are nil.
+func newDeployment(
+	aquarium *funv1alpha1.Aquarium,
+	location *funv1alpha1.Location,
+	replicas *int32,
+	defaultImage string,
+) *appsv1.Deployment {
+	deploy := &appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: appsv1.SchemeGroupVersion.String(),
 			Kind:       "Deployment",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      aquarium.Name,
-			Namespace: aquarium.Namespace,
-			Labels: map[string]string{
-				AppKey:    AquariumValue,
-				LocatedAt: aquarium.Spec.Location,
-			},
-			OwnerReferences: []metav1.OwnerReference{{
-				APIVersion:         aquarium.APIVersion,
-				Kind:               aquarium.Kind,
-				Name:               aquarium.Name,
-				UID:                aquarium.UID,
-				Controller:         pointer.Bool(true),
-				BlockOwnerDeletion: pointer.Bool(true),
-			}},
+			Name:            aquarium.Name,
+			Namespace:       aquarium.Namespace,
+			Labels:          aquariumLabels(aquarium),
+			OwnerReferences: []metav1.OwnerReference{ownerReference(aquarium)},
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: pointer.Int32(aquarium.Spec.NumTanks),
 			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": "Aquarium",
-				},
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app": "Aquarium",
-					},
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{{
-						Name:    "aquarium",
-						Image:   "wernight/funbox",
-						Command: []string{"sleep", "10000"},
-					}},
-				},
+				MatchLabels: trackSelectorLabels(aquarium, StableTrack),
 			},
+			Template: newPodTemplate(aquarium, location, defaultImage),
 		},
 	}
+	deploy.Labels[TrackKey] = StableTrack
+	deploy.Spec.Template.Labels[TrackKey] = StableTrack
+
+	applyRolloutStrategy(deploy, aquarium.Spec.Rollout)
+	deploy.Spec.Replicas = replicas
+
+	return deploy
+}
+
+// newPodTemplate builds the pod template of the tanks of an aquarium, whatever
+// the workload running them. The tanks are scheduled at the aquarium's location
+// when it is mapped, and serve visitors when the aquarium is exposed.
+func newPodTemplate(
+	aquarium *funv1alpha1.Aquarium,
+	location *funv1alpha1.Location,
+	defaultImage string,
+) corev1.PodTemplateSpec {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: aquariumLabels(aquarium),
+		},
+		Spec: corev1.PodSpec{
+			Containers:       []corev1.Container{newTankContainer(&aquarium.Spec.Tank, defaultImage)},
+			ImagePullSecrets: aquarium.Spec.Tank.ImagePullSecrets,
+		},
+	}
+
+	if exposure := aquarium.Spec.Exposure; exposure != nil {
+		template.Spec.Containers[0].Ports = []corev1.ContainerPort{{
+			Name:          TankPortName,
+			ContainerPort: exposure.Port,
+			Protocol:      corev1.ProtocolTCP,
+		}}
+	}
+	applyLocation(&template.Spec, location)
+
+	return template
+}
+
+// ownerReference returns the controller reference put on objects owned by an aquarium.
+func ownerReference(aquarium *funv1alpha1.Aquarium) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion:         funv1alpha1.GroupVersion.String(),
+		Kind:               "Aquarium",
+		Name:               aquarium.Name,
+		UID:                aquarium.UID,
+		Controller:         pointer.Bool(true),
+		BlockOwnerDeletion: pointer.Bool(true),
+	}
+}
+
+// newTankContainer builds the tank container from the aquarium's tank template.
+// An empty image falls back to defaultImage, or else to the default funbox tank
+// so objects created before the template existed keep running the same pod.
+func newTankContainer(tank *funv1alpha1.TankTemplate, defaultImage string) corev1.Container {
+	container := corev1.Container{
+		Name:            TankContainerName,
+		Image:           tank.Image,
+		Command:         tank.Command,
+		Args:            tank.Args,
+		Env:             tank.Env,
+		Resources:       tank.Resources,
+		ImagePullPolicy: tank.ImagePullPolicy,
+	}
+
+	if container.Image == "" {
+		container.Image = orDefault(defaultImage, funv1alpha1.DefaultTankImage)
+		if container.Image == funv1alpha1.DefaultTankImage && len(container.Command) == 0 {
+			container.Command = funv1alpha1.DefaultTankCommand
+		}
+	}
+
+	if container.ImagePullPolicy == "" {
+		container.ImagePullPolicy = corev1.PullIfNotPresent
+	}
+
+	return container
+}
+
+// orDefault returns value, or def when value is the zero value.
+func orDefault[T comparable](value, def T) T {
+	var zero T
+	if value == zero {
+		return def
+	}
+
+	return value
 }
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// reconcileAutoscaler applies the HorizontalPodAutoscaler of an aquarium, or
+// removes it once autoscaling is turned off. The autoscaler is removed during
+// maintenance windows too so it doesn't scale the tanks back up.
+func (r *AquariumReconciler) reconcileAutoscaler(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	maintenance maintenanceState,
+) error {
+	if aquarium.Spec.Autoscaling == nil || maintenance.Window != "" {
+		return r.deleteAutoscaler(ctx, aquarium)
+	}
+
+	if err := r.Patch(
+		ctx,
+		newHorizontalPodAutoscaler(aquarium),
+		client.Apply,
+		client.ForceOwnership,
+		client.FieldOwner(AquariumOperator),
+	); err != nil {
+		return fmt.Errorf("failed to apply horizontal pod autoscaler: %w", err)
+	}
+
+	return nil
+}
+
+// deleteAutoscaler deletes the HorizontalPodAutoscaler owned by an aquarium, if any.
+func (r *AquariumReconciler) deleteAutoscaler(ctx context.Context, aquarium *funv1alpha1.Aquarium) error {
+	var hpa autoscalingv2.HorizontalPodAutoscaler
+	if err := r.Get(ctx, client.ObjectKeyFromObject(aquarium), &hpa); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if !metav1.IsControlledBy(&hpa, aquarium) {
+		return nil
+	}
+
+	if err := r.Delete(ctx, &hpa); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete horizontal pod autoscaler: %w", err)
+	}
+
+	return nil
+}
+
+// handOverReplicas keeps the replicas of a Deployment the operator stops
+// applying, when autoscaling is turned on or a maintenance window of an
+// autoscaled aquarium closes. Dropping them from the apply would otherwise
+// reset the tanks to a single one until the autoscaler catches up, so they
+// are first handed to a separate field owner the autoscaler then takes over.
+func (r *AquariumReconciler) handOverReplicas(ctx context.Context, live, desired *appsv1.Deployment) error {
+	if live == nil || live.Spec.Replicas == nil || desired.Spec.Replicas != nil ||
+		!appliesReplicas(live, AquariumOperator) {
+		return nil
+	}
+
+	handover := &unstructured.Unstructured{}
+	handover.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	handover.SetName(live.Name)
+	handover.SetNamespace(live.Namespace)
+	if err := unstructured.SetNestedField(
+		handover.Object, int64(*live.Spec.Replicas), "spec", "replicas"); err != nil {
+		return err
+	}
+
+	if err := r.Patch(ctx, handover, client.Apply, client.FieldOwner(ReplicasHandover)); err != nil {
+		return fmt.Errorf("failed to hand over replicas of deployment %s: %w", live.Name, err)
+	}
+
+	return nil
+}
+
+// appliesReplicas reports whether a field manager applies the replicas of a
+// Deployment.
+func appliesReplicas(deploy *appsv1.Deployment, manager string) bool {
+	for _, entry := range deploy.ManagedFields {
+		if entry.Manager != manager || entry.Operation != metav1.ManagedFieldsOperationApply ||
+			entry.FieldsV1 == nil {
+			continue
+		}
+
+		var fields struct {
+			Spec map[string]json.RawMessage `json:"f:spec"`
+		}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if _, ok := fields.Spec["f:replicas"]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newHorizontalPodAutoscaler(aquarium *funv1alpha1.Aquarium) *autoscalingv2.HorizontalPodAutoscaler {
+	autoscaling := aquarium.Spec.Autoscaling
+
+	minTanks := autoscaling.MinTanks
+	if minTanks == nil {
+		minTanks = pointer.Int32(1)
+	}
+
+	targetCPU := autoscaling.TargetCPUUtilizationPercentage
+	if targetCPU == nil {
+		targetCPU = pointer.Int32(funv1alpha1.DefaultTargetCPUUtilizationPercentage)
+	}
+
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: autoscalingv2.SchemeGroupVersion.String(),
+			Kind:       "HorizontalPodAutoscaler",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            aquarium.Name,
+			Namespace:       aquarium.Namespace,
+			Labels:          aquariumLabels(aquarium),
+			OwnerReferences: []metav1.OwnerReference{ownerReference(aquarium)},
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: appsv1.SchemeGroupVersion.String(),
+				Kind:       string(workloadKind(aquarium)),
+				Name:       aquarium.Name,
+			},
+			MinReplicas: minTanks,
+			MaxReplicas: autoscaling.MaxTanks,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{
+					Name: corev1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{
+						Type:               autoscalingv2.UtilizationMetricType,
+						AverageUtilization: targetCPU,
+					},
+				},
+			}},
+		},
+	}
+}
+
+// desiredTanks is the number of tanks the aquarium should be running, as its
+// schedule has it right now. While autoscaling, that is whatever the
+// autoscaler last set on the Deployment.
+func desiredTanks(aquarium *funv1alpha1.Aquarium, deploy *appsv1.Deployment) int32 {
+	if aquarium.Spec.Autoscaling != nil && deploy != nil && deploy.Spec.Replicas != nil {
+		return *deploy.Spec.Replicas
+	}
+
+	return evaluateSchedule(aquarium, time.Now()).Tanks
+}
@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// Violation is a combination of fish that can't live in the same tank.
+type Violation struct {
+	// Species are the species involved.
+	Species []string
+	Message string
+}
+
+// EvaluateCompatibility checks the fish placed in an aquarium against the
+// profiles of their species, keyed by species. Fish without a tank may end up
+// in any tank, so they are checked against the fish of every tank. Species
+// without a profile are assumed to get along with everyone.
+func EvaluateCompatibility(
+	aquarium *funv1alpha1.Aquarium,
+	fish []funv1alpha1.Fish,
+	profiles map[string]funv1alpha1.SpeciesProfileSpec,
+) []Violation {
+	violations := map[string]Violation{}
+	add := func(v Violation) {
+		violations[v.Message] = v
+	}
+
+	for i := range fish {
+		for j := i + 1; j < len(fish); j++ {
+			a, b := &fish[i], &fish[j]
+			if a.Spec.Species == b.Spec.Species || !mayShareTank(a, b) {
+				continue
+			}
+			if a.Spec.Species > b.Spec.Species {
+				a, b = b, a
+			}
+
+			profileA, okA := profiles[a.Spec.Species]
+			profileB, okB := profiles[b.Spec.Species]
+			if !okA || !okB {
+				continue
+			}
+
+			for _, message := range pairViolations(a.Spec.Species, profileA, b.Spec.Species, profileB) {
+				add(Violation{Species: []string{a.Spec.Species, b.Spec.Species}, Message: message})
+			}
+		}
+	}
+
+	for _, v := range densityViolations(aquarium, fish, profiles) {
+		add(v)
+	}
+
+	sorted := make([]Violation, 0, len(violations))
+	for _, v := range violations {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Message < sorted[j].Message
+	})
+
+	return sorted
+}
+
+// mayShareTank reports whether two groups of fish could end up in the same tank.
+func mayShareTank(a, b *funv1alpha1.Fish) bool {
+	return a.Spec.Tank == nil || b.Spec.Tank == nil || *a.Spec.Tank == *b.Spec.Tank
+}
+
+// pairViolations returns why two species can't share a tank.
+func pairViolations(
+	speciesA string,
+	a funv1alpha1.SpeciesProfileSpec,
+	speciesB string,
+	b funv1alpha1.SpeciesProfileSpec,
+) []string {
+	var messages []string
+
+	if a.WaterType != b.WaterType {
+		messages = append(messages, fmt.Sprintf("%s needs %s water but %s needs %s water",
+			speciesA, a.WaterType, speciesB, b.WaterType))
+	}
+
+	if a.Temperature.MaxCelsius < b.Temperature.MinCelsius || b.Temperature.MaxCelsius < a.Temperature.MinCelsius {
+		messages = append(messages, fmt.Sprintf("%s needs %d-%d°C but %s needs %d-%d°C",
+			speciesA, a.Temperature.MinCelsius, a.Temperature.MaxCelsius,
+			speciesB, b.Temperature.MinCelsius, b.Temperature.MaxCelsius))
+	}
+
+	if message, ok := temperamentViolation(speciesA, a.Temperament, speciesB, b.Temperament); ok {
+		messages = append(messages, message)
+	}
+	if message, ok := temperamentViolation(speciesB, b.Temperament, speciesA, a.Temperament); ok {
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+// temperamentViolation reports whether the first species would attack the second.
+func temperamentViolation(
+	attacker string,
+	attackerTemperament funv1alpha1.Temperament,
+	victim string,
+	victimTemperament funv1alpha1.Temperament,
+) (string, bool) {
+	switch {
+	case attackerTemperament == funv1alpha1.Predator &&
+		(victimTemperament == funv1alpha1.Peaceful || victimTemperament == funv1alpha1.SemiAggressive):
+		return fmt.Sprintf("%s preys on %s", attacker, victim), true
+	case attackerTemperament == funv1alpha1.Aggressive && victimTemperament == funv1alpha1.Peaceful:
+		return fmt.Sprintf("%s harasses %s", attacker, victim), true
+	}
+
+	return "", false
+}
+
+// densityViolations returns the species that would crowd a tank. Fish without
+// a tank are assumed to be spread evenly across the tanks.
+func densityViolations(
+	aquarium *funv1alpha1.Aquarium,
+	fish []funv1alpha1.Fish,
+	profiles map[string]funv1alpha1.SpeciesProfileSpec,
+) []Violation {
+	tanks := tankCapacity(aquarium)
+	if tanks < 1 {
+		tanks = 1
+	}
+
+	assigned := map[string]map[int32]int32{}
+	unassigned := map[string]int32{}
+	for i := range fish {
+		species := fish[i].Spec.Species
+		if tank := fish[i].Spec.Tank; tank != nil {
+			if assigned[species] == nil {
+				assigned[species] = map[int32]int32{}
+			}
+			assigned[species][*tank] += fish[i].Spec.Count
+		} else {
+			unassigned[species] += fish[i].Spec.Count
+		}
+	}
+
+	var violations []Violation
+	for species, profile := range profiles {
+		if profile.MaxPerTank == nil {
+			continue
+		}
+
+		spread := (unassigned[species] + tanks - 1) / tanks
+		for tank := int32(0); tank < tanks; tank++ {
+			count := assigned[species][tank] + spread
+			if count > *profile.MaxPerTank {
+				violations = append(violations, Violation{
+					Species: []string{species},
+					Message: fmt.Sprintf("tank %d would hold %d %s, more than the %d allowed",
+						tank, count, species, *profile.MaxPerTank),
+				})
+			}
+		}
+	}
+
+	return violations
+}
+
+// ProfilesBySpecies keys species profiles by the species they are named after.
+func ProfilesBySpecies(profiles []funv1alpha1.SpeciesProfile) map[string]funv1alpha1.SpeciesProfileSpec {
+	bySpecies := make(map[string]funv1alpha1.SpeciesProfileSpec, len(profiles))
+	for _, profile := range profiles {
+		bySpecies[profile.Name] = profile.Spec
+	}
+
+	return bySpecies
+}
+
+// setCompatibilityCondition flags fish in the aquarium that can't live together.
+func (r *AquariumReconciler) setCompatibilityCondition(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	fish []funv1alpha1.Fish,
+) error {
+	var profiles funv1alpha1.SpeciesProfileList
+	if err := r.List(ctx, &profiles); err != nil {
+		return fmt.Errorf("failed to list species profiles: %w", err)
+	}
+
+	violations := EvaluateCompatibility(aquarium, fish, ProfilesBySpecies(profiles.Items))
+	if len(violations) == 0 {
+		setCondition(aquarium, CompatibilityViolation, metav1.ConditionFalse, SpeciesCompatible,
+			"All fish can live together")
+		return nil
+	}
+
+	messages := make([]string, 0, len(violations))
+	for _, v := range violations {
+		messages = append(messages, v.Message)
+	}
+	setCondition(aquarium, CompatibilityViolation, metav1.ConditionTrue, IncompatibleSpecies,
+		strings.Join(messages, "; "))
+
+	return nil
+}
+
+// aquariaWithSpecies maps a SpeciesProfile to the aquaria holding fish of its species.
+func (r *AquariumReconciler) aquariaWithSpecies(ctx context.Context, obj client.Object) []reconcile.Request {
+	var fish funv1alpha1.FishList
+	if err := r.List(ctx, &fish, client.MatchingFields{fishSpeciesIndexKey: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list fish of species", "species", obj.GetName())
+		return nil
+	}
+
+	seen := map[types.NamespacedName]bool{}
+	var requests []reconcile.Request
+	for i := range fish.Items {
+		key := types.NamespacedName{Namespace: fish.Items[i].Namespace, Name: fish.Items[i].Spec.Aquarium}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		requests = append(requests, reconcile.Request{NamespacedName: key})
+	}
+
+	return requests
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// aquariumConditionTypes are the conditions of an aquarium owned by the
+// operator. Conditions of other types belong to other controllers and are
+// left alone when the status is applied.
+var aquariumConditionTypes = []string{
+	AquariumReady,
+	Available,
+	Progressing,
+	Degraded,
+	ReconcileError,
+	Schedulable,
+	CompatibilityViolation,
+	MissedFeeding,
+	WaterQualityOK,
+	InMaintenance,
+	Drifted,
+	DisruptionProtected,
+}
+
+// setStatusConditions maintains the full condition set of an aquarium.
+// Conditions are updated in place so LastTransitionTime only moves when a
+// condition's status actually changes.
+func setStatusConditions(
+	aquarium *funv1alpha1.Aquarium,
+	deploy *appsv1.Deployment,
+	report healthReport,
+	reconcileErr error,
+) {
+	setReadyCondition(aquarium, report)
+	setAvailableCondition(aquarium, report)
+	setProgressingCondition(aquarium, deploy)
+	setDegradedCondition(aquarium, report)
+	setReconcileErrorCondition(aquarium, reconcileErr)
+}
+
+func setReadyCondition(aquarium *funv1alpha1.Aquarium, report healthReport) {
+	setCondition(aquarium, AquariumReady, report.Status, report.Reason, report.Message)
+}
+
+// setAvailableCondition marks the aquarium available while enough tanks are
+// ready for the fish to be at least kinda healthy.
+func setAvailableCondition(aquarium *funv1alpha1.Aquarium, report healthReport) {
+	status := metav1.ConditionFalse
+	switch report.Health {
+	case funv1alpha1.Healthy, funv1alpha1.KindOfHealthy:
+		status = metav1.ConditionTrue
+	case funv1alpha1.Unknown:
+		status = metav1.ConditionUnknown
+	}
+
+	setCondition(aquarium, Available, status, report.Reason, report.Message)
+}
+
+func setProgressingCondition(aquarium *funv1alpha1.Aquarium, deploy *appsv1.Deployment) {
+	if deploy == nil {
+		setCondition(aquarium, Progressing, metav1.ConditionUnknown, AquariumHealthUnknown,
+			"The aquarium has no tanks yet")
+		return
+	}
+
+	if rolloutProgressing(deploy) {
+		setCondition(aquarium, Progressing, metav1.ConditionTrue, RolloutInProgress,
+			"The tanks are rolling out")
+		return
+	}
+
+	setCondition(aquarium, Progressing, metav1.ConditionFalse, RolloutComplete,
+		"All tanks run the latest template")
+}
+
+func setDegradedCondition(aquarium *funv1alpha1.Aquarium, report healthReport) {
+	status := metav1.ConditionTrue
+	switch report.Health {
+	case funv1alpha1.Healthy:
+		status = metav1.ConditionFalse
+	case funv1alpha1.Unknown:
+		status = metav1.ConditionUnknown
+	}
+
+	setCondition(aquarium, Degraded, status, report.Reason, report.Message)
+}
+
+func setReconcileErrorCondition(aquarium *funv1alpha1.Aquarium, reconcileErr error) {
+	if reconcileErr != nil {
+		setCondition(aquarium, ReconcileError, metav1.ConditionTrue, ReconcileFailed, reconcileErr.Error())
+		return
+	}
+
+	setCondition(aquarium, ReconcileError, metav1.ConditionFalse, ReconcileSucceeded,
+		"The aquarium was reconciled successfully")
+}
+
+func setCondition(
+	aquarium *funv1alpha1.Aquarium,
+	conditionType string,
+	status metav1.ConditionStatus,
+	reason, message string,
+) {
+	apimeta.SetStatusCondition(&aquarium.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: aquarium.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
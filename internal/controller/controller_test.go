@@ -3,14 +3,31 @@ package controller_test
 import (
 	"context"
 	"reflect"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+	"github.com/tydanny/aquarium-operator/internal/controller"
 )
 
 var _ = Describe("Controller", func() {
@@ -61,7 +78,24 @@ var _ = Describe("Controller", func() {
 				return k8sClient.Get(ctx, aquariumLookupKey, createdDeployment)
 			}).Should(Succeed())
 			Expect(*createdDeployment.Spec.Replicas).To(Equal(aquarium.Spec.NumTanks))
-			Expect(createdDeployment.Labels).To(HaveKeyWithValue("app", "Aquarium"))
+			Expect(createdDeployment.Labels).To(HaveKeyWithValue(controller.AppManagedByKey, controller.AquariumOperator))
+			Expect(createdDeployment.Labels).To(HaveKeyWithValue(controller.AppInstanceKey, aquarium.Name))
+			Expect(createdDeployment.Spec.Selector.MatchLabels).To(Equal(map[string]string{
+				controller.AppNameKey:     controller.AquariumAppName,
+				controller.AppInstanceKey: aquarium.Name,
+				controller.AquariumUIDKey: string(createdAquarium.UID),
+				controller.TrackKey:       controller.StableTrack,
+			}))
+			Expect(createdDeployment.Spec.Template.Labels).To(
+				HaveKeyWithValue(controller.AquariumUIDKey, string(createdAquarium.UID)),
+			)
+
+			By("Checking that the tank defaults to the configured image")
+			Expect(createdAquarium.Spec.Tank.Image).To(BeEmpty())
+			Expect(createdDeployment.Spec.Template.Spec.Containers).To(HaveLen(1))
+			tank := createdDeployment.Spec.Template.Spec.Containers[0]
+			Expect(tank.Image).To(Equal(TankImage))
+			Expect(tank.Command).To(BeEmpty())
 
 			By("Checking that the aquarium status is updated")
 			createdDeployment.Status.Replicas = 2
@@ -75,6 +109,1994 @@ var _ = Describe("Controller", func() {
 
 				return createdAquarium.Status.FishHealth, nil
 			}).Should(Equal(funv1alpha1.Healthy))
+
+			By("Checking the aquarium's conditions")
+			Expect(createdAquarium.Status.ObservedGeneration).To(Equal(createdAquarium.Generation))
+			Expect(apimeta.IsStatusConditionTrue(createdAquarium.Status.Conditions, controller.Available)).To(BeTrue())
+			Expect(apimeta.IsStatusConditionFalse(createdAquarium.Status.Conditions, controller.Degraded)).To(BeTrue())
+			Expect(apimeta.IsStatusConditionFalse(createdAquarium.Status.Conditions, controller.ReconcileError)).To(BeTrue())
+			Expect(apimeta.FindStatusCondition(createdAquarium.Status.Conditions, controller.Progressing)).NotTo(BeNil())
+
+			By("Checking that the Available condition keeps its transition time")
+			available := apimeta.FindStatusCondition(createdAquarium.Status.Conditions, controller.Available)
+			createdDeployment.Status.AvailableReplicas = 2
+			Expect(k8sClient.Status().Update(ctx, createdDeployment)).To(Succeed())
+			Eventually(ctx, func() (int32, error) {
+				if err := k8sClient.Get(ctx, aquariumLookupKey, createdAquarium); err != nil {
+					return 0, err
+				}
+
+				return createdAquarium.Status.NumTanksReady, nil
+			}).Should(Equal(int32(2)))
+			Expect(apimeta.FindStatusCondition(createdAquarium.Status.Conditions, controller.Available).LastTransitionTime).
+				To(Equal(available.LastTransitionTime))
+
+			By("Checking that the lifecycle was recorded in events")
+			Eventually(ctx, eventReasons(ctx, aquarium.Name)).Should(ContainElements(
+				controller.DeploymentCreated,
+				controller.AquariumIsHealthy,
+			))
+
+			By("Checking that the aquarium's metrics are exported")
+			aquariumMetrics := map[string]string{"namespace": AquariumNamespace, "aquarium": aquarium.Name}
+			Expect(metricValues("aquarium_tanks_desired", aquariumMetrics)).To(ConsistOf(2.0))
+			Expect(metricValues("aquarium_tanks_ready", aquariumMetrics)).To(ConsistOf(2.0))
+			Expect(metricValues("aquarium_fish_health", map[string]string{
+				"namespace": AquariumNamespace,
+				"aquarium":  aquarium.Name,
+				"health":    string(funv1alpha1.Healthy),
+			})).To(ConsistOf(1.0))
+			Expect(metricValues("aquarium_fish_health_transitions_total", map[string]string{
+				"namespace": AquariumNamespace,
+				"aquarium":  aquarium.Name,
+				"to":        string(funv1alpha1.Healthy),
+			})).NotTo(BeEmpty())
 		})
 	})
-})
+
+	Context("When the aquarium has a tank template", func() {
+		It("should run the tank container from the template", func() {
+			ctx := context.Background()
+
+			By("Creating an aquarium with a custom tank")
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "custom-tank-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+					Tank: funv1alpha1.TankTemplate{
+						Image: "busybox:1.36",
+						Args:  []string{"sleep", "infinity"},
+						Env:   []corev1.EnvVar{{Name: "FISH", Value: "nemo"}},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU: resource.MustParse("10m"),
+							},
+						},
+						ImagePullSecrets: []corev1.LocalObjectReference{{Name: "regcred"}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			By("Checking that the deployment uses the tank template")
+			createdDeployment := &appsv1.Deployment{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: aquarium.Name, Namespace: AquariumNamespace}, createdDeployment)
+			}).Should(Succeed())
+
+			podSpec := createdDeployment.Spec.Template.Spec
+			Expect(podSpec.ImagePullSecrets).To(ConsistOf(corev1.LocalObjectReference{Name: "regcred"}))
+			Expect(podSpec.Containers).To(HaveLen(1))
+			tank := podSpec.Containers[0]
+			Expect(tank.Image).To(Equal("busybox:1.36"))
+			Expect(tank.Command).To(BeEmpty())
+			Expect(tank.Args).To(Equal([]string{"sleep", "infinity"}))
+			Expect(tank.Env).To(ContainElement(corev1.EnvVar{Name: "FISH", Value: "nemo"}))
+			Expect(tank.ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+			Expect(tank.Resources.Requests.Cpu().String()).To(Equal("10m"))
+		})
+	})
+
+	Context("When only some tanks are ready", func() {
+		It("should report the fish as kinda healthy above the degraded threshold", func() {
+			ctx := context.Background()
+
+			By("Creating an aquarium with four tanks")
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "degraded-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 4,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			aquariumLookupKey := types.NamespacedName{Name: aquarium.Name, Namespace: AquariumNamespace}
+			createdDeployment := &appsv1.Deployment{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, aquariumLookupKey, createdDeployment)
+			}).Should(Succeed())
+
+			fishHealth := func() (funv1alpha1.FishHealth, error) {
+				createdAquarium := &funv1alpha1.Aquarium{}
+				if err := k8sClient.Get(ctx, aquariumLookupKey, createdAquarium); err != nil {
+					return "", err
+				}
+
+				return createdAquarium.Status.FishHealth, nil
+			}
+
+			By("Marking half of the tanks ready")
+			createdDeployment.Status.ObservedGeneration = createdDeployment.Generation
+			createdDeployment.Status.Replicas = 4
+			createdDeployment.Status.UpdatedReplicas = 4
+			createdDeployment.Status.ReadyReplicas = 2
+			Expect(k8sClient.Status().Update(ctx, createdDeployment)).To(Succeed())
+			Eventually(ctx, fishHealth).Should(Equal(funv1alpha1.KindOfHealthy))
+
+			By("Dropping below the degraded threshold")
+			createdDeployment.Status.ReadyReplicas = 1
+			Expect(k8sClient.Status().Update(ctx, createdDeployment)).To(Succeed())
+			Eventually(ctx, fishHealth).Should(Equal(funv1alpha1.Unhealthy))
+		})
+	})
+
+	Context("When the aquarium is scaled", func() {
+		It("should scale the tanks through the scale subresource", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "scaled-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			By("Checking that the scale subresource reports the tank selector")
+			scale := &autoscalingv1.Scale{}
+			Eventually(ctx, func() (string, error) {
+				if err := k8sClient.SubResource("scale").Get(ctx, aquarium, scale); err != nil {
+					return "", err
+				}
+
+				return scale.Status.Selector, nil
+			}).ShouldNot(BeEmpty())
+
+			By("Scaling the aquarium to three tanks")
+			scale.Spec.Replicas = 3
+			Expect(k8sClient.SubResource("scale").Update(ctx, aquarium, client.WithSubResourceBody(scale))).To(Succeed())
+
+			Eventually(ctx, func() (int32, error) {
+				deploy := &appsv1.Deployment{}
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deploy); err != nil {
+					return 0, err
+				}
+
+				return *deploy.Spec.Replicas, nil
+			}).Should(Equal(int32(3)))
+		})
+
+		It("should leave the tanks to its autoscaler", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "autoscaled-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+					Autoscaling: &funv1alpha1.AutoscalingSpec{
+						MinTanks: pointer.Int32(2),
+						MaxTanks: 5,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			By("Checking that the aquarium owns an autoscaler for its deployment")
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), hpa)
+			}).Should(Succeed())
+			Expect(hpa.Spec.ScaleTargetRef.Kind).To(Equal("Deployment"))
+			Expect(hpa.Spec.ScaleTargetRef.Name).To(Equal(aquarium.Name))
+			Expect(*hpa.Spec.MinReplicas).To(Equal(int32(2)))
+			Expect(hpa.Spec.MaxReplicas).To(Equal(int32(5)))
+
+			By("Scaling the deployment like the autoscaler would")
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deploy)).To(Succeed())
+			deploy.Spec.Replicas = pointer.Int32(4)
+			Expect(k8sClient.Update(ctx, deploy)).To(Succeed())
+
+			Consistently(ctx, func() (int32, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deploy); err != nil {
+					return 0, err
+				}
+
+				return *deploy.Spec.Replicas, nil
+			}).Should(Equal(int32(4)))
+
+			By("Turning autoscaling off")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Spec.Autoscaling = nil
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+
+			Eventually(ctx, func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), hpa)
+				return apierrors.IsNotFound(err)
+			}).Should(BeTrue())
+		})
+
+		It("should keep the running tanks when autoscaling is turned on", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "newly-autoscaled-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 3,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			deploy := &appsv1.Deployment{}
+			Eventually(ctx, func() (int32, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deploy); err != nil {
+					return 0, err
+				}
+
+				return *deploy.Spec.Replicas, nil
+			}).Should(Equal(int32(3)))
+
+			By("Turning autoscaling on")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Spec.Autoscaling = &funv1alpha1.AutoscalingSpec{MaxTanks: 5}
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), hpa)
+			}).Should(Succeed())
+
+			By("Checking that the tanks aren't scaled down before the autoscaler acts")
+			Consistently(ctx, func() (int32, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deploy); err != nil {
+					return 0, err
+				}
+
+				return *deploy.Spec.Replicas, nil
+			}).Should(Equal(int32(3)))
+		})
+	})
+
+	Context("When the aquarium is at a mapped location", func() {
+		It("should schedule the tanks onto the location's nodes", func() {
+			ctx := context.Background()
+
+			By("Mapping the location to nodes")
+			location := &funv1alpha1.Location{
+				ObjectMeta: metav1.ObjectMeta{Name: "tide-pool"},
+				Spec: funv1alpha1.LocationSpec{
+					NodeSelector: map[string]string{"fun.tydanny.com/pool": "tide"},
+					Tolerations: []corev1.Toleration{{
+						Key:      "fun.tydanny.com/wet",
+						Operator: corev1.TolerationOpExists,
+						Effect:   corev1.TaintEffectNoSchedule,
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, location)).Should(Succeed())
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tide-pool-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: location.Name,
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			By("Checking that the tanks are scheduled at the location")
+			deploy := &appsv1.Deployment{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deploy)
+			}).Should(Succeed())
+			Expect(deploy.Spec.Template.Spec.NodeSelector).To(Equal(location.Spec.NodeSelector))
+			Expect(deploy.Spec.Template.Spec.Tolerations).To(Equal(location.Spec.Tolerations))
+
+			By("Checking that the aquarium reports no matching nodes")
+			schedulable := func() (metav1.ConditionStatus, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium); err != nil {
+					return "", err
+				}
+
+				condition := apimeta.FindStatusCondition(aquarium.Status.Conditions, controller.Schedulable)
+				if condition == nil {
+					return "", nil
+				}
+				return condition.Status, nil
+			}
+			Eventually(ctx, schedulable).Should(Equal(metav1.ConditionFalse))
+
+			By("Adding a tainted node at the location")
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "tide-pool-node",
+					Labels: map[string]string{"fun.tydanny.com/pool": "tide"},
+				},
+				Spec: corev1.NodeSpec{
+					Taints: []corev1.Taint{{
+						Key:    "fun.tydanny.com/wet",
+						Value:  "true",
+						Effect: corev1.TaintEffectNoSchedule,
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, node)).Should(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, node)).To(Succeed())
+			})
+
+			Eventually(ctx, schedulable).Should(Equal(metav1.ConditionTrue))
+		})
+
+		It("should map locations however they are spelled", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "kelp-forest-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Kelp Forest",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			By("Mapping the location once the aquarium is built")
+			location := &funv1alpha1.Location{
+				ObjectMeta: metav1.ObjectMeta{Name: "kelp-forest"},
+				Spec: funv1alpha1.LocationSpec{
+					NodeSelector: map[string]string{"fun.tydanny.com/pool": "kelp"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, location)).Should(Succeed())
+
+			By("Checking that the tanks are scheduled at the location")
+			deploy := &appsv1.Deployment{}
+			Eventually(ctx, func() (map[string]string, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deploy); err != nil {
+					return nil, err
+				}
+
+				return deploy.Spec.Template.Spec.NodeSelector, nil
+			}).Should(Equal(location.Spec.NodeSelector))
+		})
+	})
+
+	Context("When fish are placed in aquaria", func() {
+		It("should count the placed fish and refuse the others", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "reef-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 2,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			newFish := func(name, aquarium, species string, count int32, tank *int32) *funv1alpha1.Fish {
+				return &funv1alpha1.Fish{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: AquariumNamespace},
+					Spec: funv1alpha1.FishSpec{
+						Aquarium: aquarium,
+						Species:  species,
+						Count:    count,
+						Tank:     tank,
+					},
+				}
+			}
+			clownfish := newFish("clownfish", aquarium.Name, "clownfish", 3, pointer.Int32(1))
+			blueTang := newFish("blue-tang", aquarium.Name, "blue-tang", 2, nil)
+			stray := newFish("stray", aquarium.Name, "clownfish", 4, pointer.Int32(2))
+			lost := newFish("lost", "atlantis", "clownfish", 1, nil)
+			for _, fish := range []*funv1alpha1.Fish{clownfish, blueTang, stray, lost} {
+				Expect(k8sClient.Create(ctx, fish)).Should(Succeed())
+			}
+
+			placedReason := func(fish *funv1alpha1.Fish) func() (string, error) {
+				return func() (string, error) {
+					if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(fish), fish); err != nil {
+						return "", err
+					}
+
+					condition := apimeta.FindStatusCondition(fish.Status.Conditions, controller.Placed)
+					if condition == nil {
+						return "", nil
+					}
+					return condition.Reason, nil
+				}
+			}
+
+			By("Checking that fish in existing tanks are placed")
+			Eventually(ctx, placedReason(clownfish)).Should(Equal(controller.FishPlaced))
+			Eventually(ctx, placedReason(blueTang)).Should(Equal(controller.FishPlaced))
+
+			By("Checking that placements in missing tanks and aquaria are refused")
+			Eventually(ctx, placedReason(stray)).Should(Equal(controller.TankNotFound))
+			Eventually(ctx, placedReason(lost)).Should(Equal(controller.AquariumNotFound))
+
+			By("Checking the aquarium's population")
+			Eventually(ctx, func() (funv1alpha1.Population, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status.Population, err
+			}).Should(Equal(funv1alpha1.Population{
+				TotalFish: 5,
+				Species: []funv1alpha1.SpeciesCount{
+					{Species: "blue-tang", Count: 2},
+					{Species: "clownfish", Count: 3},
+				},
+			}))
+
+			By("Checking that fish are placed once their tank exists")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Spec.NumTanks = 3
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+			Eventually(ctx, placedReason(stray)).Should(Equal(controller.FishPlaced))
+			Eventually(ctx, func() (int32, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status.Population.TotalFish, err
+			}).Should(Equal(int32(9)))
+		})
+	})
+
+	Context("When incompatible species share a tank", func() {
+		It("should flag the aquarium", func() {
+			ctx := context.Background()
+
+			for _, profile := range []*funv1alpha1.SpeciesProfile{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "reef-shark"},
+					Spec: funv1alpha1.SpeciesProfileSpec{
+						Temperament: funv1alpha1.Predator,
+						WaterType:   funv1alpha1.Saltwater,
+						Temperature: funv1alpha1.TemperatureRange{MinCelsius: 22, MaxCelsius: 28},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "damselfish"},
+					Spec: funv1alpha1.SpeciesProfileSpec{
+						Temperament: funv1alpha1.Peaceful,
+						WaterType:   funv1alpha1.Saltwater,
+						Temperature: funv1alpha1.TemperatureRange{MinCelsius: 24, MaxCelsius: 27},
+					},
+				},
+			} {
+				Expect(k8sClient.Create(ctx, profile)).Should(Succeed())
+			}
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "shark-tank-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 2,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			sharks := &funv1alpha1.Fish{
+				ObjectMeta: metav1.ObjectMeta{Name: "reef-sharks", Namespace: AquariumNamespace},
+				Spec: funv1alpha1.FishSpec{
+					Aquarium: aquarium.Name,
+					Species:  "reef-shark",
+					Count:    1,
+					Tank:     pointer.Int32(0),
+				},
+			}
+			damsels := &funv1alpha1.Fish{
+				ObjectMeta: metav1.ObjectMeta{Name: "damsels", Namespace: AquariumNamespace},
+				Spec: funv1alpha1.FishSpec{
+					Aquarium: aquarium.Name,
+					Species:  "damselfish",
+					Count:    4,
+					Tank:     pointer.Int32(1),
+				},
+			}
+			Expect(k8sClient.Create(ctx, sharks)).Should(Succeed())
+			Expect(k8sClient.Create(ctx, damsels)).Should(Succeed())
+
+			compatibility := func() (*metav1.Condition, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium); err != nil {
+					return nil, err
+				}
+
+				return apimeta.FindStatusCondition(aquarium.Status.Conditions, controller.CompatibilityViolation), nil
+			}
+
+			By("Checking that species in separate tanks are compatible")
+			Eventually(ctx, compatibility).Should(HaveField("Status", metav1.ConditionFalse))
+
+			By("Moving the prey into the predator's tank")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(damsels), damsels)).To(Succeed())
+			damsels.Spec.Tank = pointer.Int32(0)
+			Expect(k8sClient.Update(ctx, damsels)).To(Succeed())
+
+			Eventually(ctx, compatibility).Should(And(
+				HaveField("Status", metav1.ConditionTrue),
+				HaveField("Reason", controller.IncompatibleSpecies),
+				HaveField("Message", ContainSubstring("reef-shark preys on damselfish")),
+			))
+		})
+	})
+
+	Context("When the aquarium has a feeding schedule", func() {
+		It("should feed the tanks through cron jobs", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "fed-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 2,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			schedule := &funv1alpha1.FeedingSchedule{
+				ObjectMeta: metav1.ObjectMeta{Name: "meals", Namespace: AquariumNamespace},
+				Spec: funv1alpha1.FeedingScheduleSpec{
+					Aquarium: aquarium.Name,
+					Feedings: []funv1alpha1.Feeding{
+						{
+							Name:           "breakfast",
+							Schedule:       "0 8 * * *",
+							Food:           "flakes",
+							PortionPerTank: resource.MustParse("5"),
+						},
+						{
+							Name:           "dinner",
+							Schedule:       "0 18 * * *",
+							Food:           "brine-shrimp",
+							PortionPerTank: resource.MustParse("2.5"),
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, schedule)).Should(Succeed())
+
+			By("Checking that every feeding gets a cron job")
+			breakfast := &batchv1.CronJob{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{
+					Name:      "meals-breakfast",
+					Namespace: AquariumNamespace,
+				}, breakfast)
+			}).Should(Succeed())
+			Expect(breakfast.Spec.Schedule).To(Equal("0 8 * * *"))
+			Expect(breakfast.Labels).To(HaveKeyWithValue(controller.AppInstanceKey, aquarium.Name))
+			Expect(breakfast.Labels).To(HaveKeyWithValue(controller.FeedingScheduleKey, schedule.Name))
+			Expect(breakfast.OwnerReferences).To(ContainElement(HaveField("Name", schedule.Name)))
+			Expect(breakfast.Spec.JobTemplate.Spec.Template.Labels).NotTo(HaveKey(controller.AquariumUIDKey))
+			Expect(breakfast.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Env).To(
+				ContainElement(corev1.EnvVar{Name: "FOOD", Value: "flakes"}),
+			)
+
+			dinner := &batchv1.CronJob{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      "meals-dinner",
+				Namespace: AquariumNamespace,
+			}, dinner)).To(Succeed())
+
+			By("Checking that the schedule reports the next feedings")
+			Eventually(ctx, func() ([]funv1alpha1.FeedingStatus, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(schedule), schedule)
+				return schedule.Status.Feedings, err
+			}).Should(HaveEach(HaveField("NextFeedingTime", Not(BeNil()))))
+			Expect(schedule.OwnerReferences).To(ContainElement(HaveField("UID", aquarium.UID)))
+
+			missedFeeding := func() (metav1.ConditionStatus, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium); err != nil {
+					return "", err
+				}
+
+				condition := apimeta.FindStatusCondition(aquarium.Status.Conditions, controller.MissedFeeding)
+				if condition == nil {
+					return "", nil
+				}
+				return condition.Status, nil
+			}
+			Eventually(ctx, missedFeeding).Should(Equal(metav1.ConditionFalse))
+
+			By("Missing a day of breakfasts")
+			twoDaysAgo := metav1.NewTime(time.Now().Add(-48 * time.Hour))
+			Eventually(ctx, func() error {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(schedule), schedule); err != nil {
+					return err
+				}
+				schedule.Status.ActiveSince = &twoDaysAgo
+				return k8sClient.Status().Update(ctx, schedule)
+			}).Should(Succeed())
+			Eventually(ctx, func() error {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(breakfast), breakfast); err != nil {
+					return err
+				}
+				breakfast.Status.LastScheduleTime = &twoDaysAgo
+				return k8sClient.Status().Update(ctx, breakfast)
+			}).Should(Succeed())
+
+			Eventually(ctx, missedFeeding).Should(Equal(metav1.ConditionTrue))
+			Expect(apimeta.FindStatusCondition(aquarium.Status.Conditions, controller.MissedFeeding).Message).
+				To(ContainSubstring("breakfast missed"))
+
+			By("Removing a feeding")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(schedule), schedule)).To(Succeed())
+			schedule.Spec.Feedings = schedule.Spec.Feedings[:1]
+			Expect(k8sClient.Update(ctx, schedule)).To(Succeed())
+			Eventually(ctx, func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dinner), dinner)
+				return apierrors.IsNotFound(err) || !dinner.DeletionTimestamp.IsZero()
+			}).Should(BeTrue())
+		})
+
+		It("should name the cron jobs of long feeding names within the limit", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "long-fed-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			schedule := &funv1alpha1.FeedingSchedule{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "the-very-long-winded-weekday-meal-plan",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.FeedingScheduleSpec{
+					Aquarium: aquarium.Name,
+					Feedings: []funv1alpha1.Feeding{{
+						Name:           "second-breakfast",
+						Schedule:       "0 10 * * 1-5",
+						Food:           "flakes",
+						PortionPerTank: resource.MustParse("1"),
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, schedule)).Should(Succeed())
+
+			cronJobs := &batchv1.CronJobList{}
+			Eventually(ctx, func() ([]batchv1.CronJob, error) {
+				err := k8sClient.List(ctx, cronJobs, client.InNamespace(AquariumNamespace),
+					client.MatchingLabels{controller.FeedingScheduleKey: schedule.Name})
+				return cronJobs.Items, err
+			}).Should(HaveLen(1))
+			Expect(len(cronJobs.Items[0].Name)).To(BeNumerically("<=", 52))
+			Expect(cronJobs.Items[0].Name).To(HavePrefix("the-very-long-winded-weekday-meal-plan-"))
+			Expect(cronJobs.Items[0].Spec.JobTemplate.Spec.Template.Spec.Containers[0].Env).To(
+				ContainElement(corev1.EnvVar{Name: "TANKS", Value: "1"}),
+			)
+		})
+	})
+
+	Context("When the water is out of range", func() {
+		It("should flag the water and lower the fish health", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "murky-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			deployment := &appsv1.Deployment{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+			}).Should(Succeed())
+			deployment.Status.Replicas = 1
+			deployment.Status.ReadyReplicas = 1
+			deployment.Status.UpdatedReplicas = 1
+			Expect(k8sClient.Status().Update(ctx, deployment)).To(Succeed())
+
+			waterQuality := func() (metav1.ConditionStatus, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium); err != nil {
+					return "", err
+				}
+
+				condition := apimeta.FindStatusCondition(aquarium.Status.Conditions, controller.WaterQualityOK)
+				if condition == nil {
+					return "", nil
+				}
+				return condition.Status, nil
+			}
+			Eventually(ctx, waterQuality).Should(Equal(metav1.ConditionUnknown))
+			Expect(aquarium.Status.FishHealth).To(Equal(funv1alpha1.Healthy))
+
+			By("Reporting alkaline water")
+			ph := resource.MustParse("9.5")
+			temperature := resource.MustParse("25")
+			reading := &funv1alpha1.WaterReading{
+				ObjectMeta: metav1.ObjectMeta{Name: "murky-tank-0", Namespace: AquariumNamespace},
+				Spec: funv1alpha1.WaterReadingSpec{
+					Aquarium:           aquarium.Name,
+					Tank:               0,
+					PH:                 &ph,
+					TemperatureCelsius: &temperature,
+				},
+			}
+			Expect(k8sClient.Create(ctx, reading)).To(Succeed())
+
+			Eventually(ctx, waterQuality).Should(Equal(metav1.ConditionFalse))
+			Expect(apimeta.FindStatusCondition(aquarium.Status.Conditions, controller.WaterQualityOK).Message).
+				To(Equal("tank 0 pH 9.5 is above 8.5"))
+			Expect(aquarium.Status.FishHealth).To(Equal(funv1alpha1.KindOfHealthy))
+
+			By("Checking that the reading is owned by the aquarium")
+			Eventually(ctx, func() ([]metav1.OwnerReference, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(reading), reading)
+				return reading.OwnerReferences, err
+			}).Should(ContainElement(HaveField("UID", aquarium.UID)))
+
+			By("Pruning readings that left the window")
+			old := &funv1alpha1.WaterReading{
+				ObjectMeta: metav1.ObjectMeta{Name: "murky-tank-0-old", Namespace: AquariumNamespace},
+				Spec: funv1alpha1.WaterReadingSpec{
+					Aquarium: aquarium.Name,
+					Tank:     0,
+					TakenAt:  &metav1.Time{Time: time.Now().Add(-2 * time.Hour)},
+					PH:       &ph,
+				},
+			}
+			Expect(k8sClient.Create(ctx, old)).To(Succeed())
+			Eventually(ctx, func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(old), old))
+			}).Should(BeTrue())
+		})
+	})
+
+	Context("When the aquarium is under maintenance", func() {
+		It("should scale the tanks down and back up", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "cleaned-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 3,
+					Location: "Atlanta",
+					Maintenance: funv1alpha1.MaintenanceSpec{
+						Windows: []funv1alpha1.MaintenanceWindow{{
+							Name:     "cleaning",
+							Schedule: "* * * * *",
+							Duration: metav1.Duration{Duration: 2 * time.Minute},
+						}},
+						Tanks: pointer.Int32(1),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			deployment := &appsv1.Deployment{}
+			replicas := func() (int32, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment); err != nil {
+					return 0, err
+				}
+				return *deployment.Spec.Replicas, nil
+			}
+			maintenance := func() (*metav1.Condition, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium); err != nil {
+					return nil, err
+				}
+				return apimeta.FindStatusCondition(aquarium.Status.Conditions, controller.InMaintenance), nil
+			}
+
+			By("Checking that the tanks are scaled to the floor during the window")
+			Eventually(ctx, replicas).Should(Equal(int32(1)))
+			Eventually(ctx, maintenance).Should(And(
+				HaveField("Status", metav1.ConditionTrue),
+				HaveField("Reason", controller.MaintenanceWindowOpen),
+			))
+			Expect(aquarium.Status.FishHealth).To(Equal(funv1alpha1.KindOfHealthy))
+			Eventually(ctx, eventReasons(ctx, aquarium.Name)).Should(ContainElement(controller.MaintenanceStarted))
+
+			By("Checking that healthy fish stay healthy during the window")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)).To(Succeed())
+			deployment.Status.Replicas = 1
+			deployment.Status.ReadyReplicas = 1
+			deployment.Status.UpdatedReplicas = 1
+			Expect(k8sClient.Status().Update(ctx, deployment)).To(Succeed())
+			Eventually(ctx, func() (funv1alpha1.FishHealth, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status.FishHealth, err
+			}).Should(Equal(funv1alpha1.Healthy))
+
+			By("Checking that the tanks resume once there is no window")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Spec.Maintenance.Windows = nil
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+			Eventually(ctx, replicas).Should(Equal(int32(3)))
+			Eventually(ctx, maintenance).Should(HaveField("Status", metav1.ConditionFalse))
+			Eventually(ctx, eventReasons(ctx, aquarium.Name)).Should(ContainElement(controller.MaintenanceEnded))
+
+			By("Pausing the aquarium")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Annotations = map[string]string{controller.PausedAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+			Eventually(ctx, maintenance).Should(HaveField("Reason", controller.ReconciliationPaused))
+
+			By("Checking that manual scaling is left alone while paused")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)).To(Succeed())
+			deployment.Spec.Replicas = pointer.Int32(0)
+			Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+			Consistently(ctx, replicas).Should(Equal(int32(0)))
+
+			By("Resuming the aquarium")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			delete(aquarium.Annotations, controller.PausedAnnotation)
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+			Eventually(ctx, replicas).Should(Equal(int32(3)))
+		})
+	})
+
+	Context("When the aquarium has a schedule", func() {
+		It("should run the tanks of the active entry", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "scheduled-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+					Schedule: []funv1alpha1.ScheduleEntry{
+						{
+							Name:     "always-open",
+							Start:    "* * * * *",
+							Duration: metav1.Duration{Duration: 2 * time.Minute},
+							Tanks:    3,
+						},
+						{
+							Name:     "also-always-open",
+							Start:    "* * * * *",
+							Duration: metav1.Duration{Duration: 2 * time.Minute},
+							Tanks:    5,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			deployment := &appsv1.Deployment{}
+			replicas := func() (int32, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment); err != nil {
+					return 0, err
+				}
+				return *deployment.Spec.Replicas, nil
+			}
+
+			By("Checking that the first active entry wins")
+			Eventually(ctx, replicas).Should(Equal(int32(3)))
+			Eventually(ctx, func() (funv1alpha1.AquariumStatus, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status, err
+			}).Should(And(
+				HaveField("EffectiveTanks", int32(3)),
+				HaveField("ActiveSchedule", "always-open"),
+			))
+
+			By("Checking that num_tanks is back in effect without an active entry")
+			aquarium.Spec.Schedule = nil
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+			Eventually(ctx, replicas).Should(Equal(int32(1)))
+			Eventually(ctx, func() (funv1alpha1.AquariumStatus, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status, err
+			}).Should(And(
+				HaveField("EffectiveTanks", int32(1)),
+				HaveField("ActiveSchedule", ""),
+			))
+		})
+	})
+
+	Context("When another controller writes the aquarium status", func() {
+		It("should leave the fields it doesn't own alone", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "shared-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+			Eventually(ctx, func() ([]metav1.Condition, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status.Conditions, err
+			}).Should(ContainElement(HaveField("Type", controller.Available)))
+
+			By("Checking that the operator applies the status")
+			Expect(aquarium.ManagedFields).To(ContainElement(And(
+				HaveField("Manager", controller.AquariumOperator),
+				HaveField("Operation", metav1.ManagedFieldsOperationApply),
+				HaveField("Subresource", "status"),
+			)))
+
+			By("Applying a condition as a fish inventory")
+			inventory := &unstructured.Unstructured{Object: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{map[string]interface{}{
+						"type":               "FishCounted",
+						"status":             string(metav1.ConditionTrue),
+						"reason":             "Counted",
+						"message":            "Every fish is accounted for",
+						"lastTransitionTime": metav1.Now().UTC().Format(time.RFC3339),
+					}},
+				},
+			}}
+			inventory.SetGroupVersionKind(funv1alpha1.GroupVersion.WithKind("Aquarium"))
+			inventory.SetName(aquarium.Name)
+			inventory.SetNamespace(aquarium.Namespace)
+			Expect(k8sClient.Status().Patch(ctx, inventory, client.Apply, client.FieldOwner("fish-inventory"))).
+				To(Succeed())
+
+			By("Checking that the condition survives a reconcile")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Spec.NumTanks = 2
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+			Eventually(ctx, func() (int64, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status.ObservedGeneration, err
+			}).Should(Equal(aquarium.Generation))
+			Expect(aquarium.Status.Conditions).To(ContainElements(
+				HaveField("Type", "FishCounted"),
+				HaveField("Type", controller.Available),
+			))
+		})
+	})
+
+	Context("When the deployment has a legacy selector", func() {
+		It("should recreate it without stopping the old tanks", func() {
+			ctx := context.Background()
+
+			legacyLabels := map[string]string{controller.AppKey: controller.AquariumValue}
+			template := corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: legacyLabels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: controller.TankContainerName, Image: "busybox"}},
+				},
+			}
+
+			By("Creating the tanks the way older operators did")
+			legacy := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "legacy-aquarium",
+					Namespace: AquariumNamespace,
+					Labels:    legacyLabels,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: legacyLabels},
+					Template: template,
+				},
+			}
+			Expect(k8sClient.Create(ctx, legacy)).Should(Succeed())
+
+			replicaSet := &appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "legacy-aquarium-5d9c8",
+					Namespace:       AquariumNamespace,
+					Labels:          legacyLabels,
+					OwnerReferences: []metav1.OwnerReference{controllerReference(legacy, "Deployment")},
+				},
+				Spec: appsv1.ReplicaSetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: legacyLabels},
+					Template: template,
+				},
+			}
+			Expect(k8sClient.Create(ctx, replicaSet)).Should(Succeed())
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "legacy-aquarium-5d9c8-x7k2p",
+					Namespace:       AquariumNamespace,
+					Labels:          legacyLabels,
+					OwnerReferences: []metav1.OwnerReference{controllerReference(replicaSet, "ReplicaSet")},
+				},
+				Spec: template.Spec,
+			}
+			Expect(k8sClient.Create(ctx, pod)).Should(Succeed())
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      legacy.Name,
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			By("Orphaning the legacy deployment, as the garbage collector would")
+			deployment := &appsv1.Deployment{}
+			Eventually(ctx, func() (types.UID, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment); err != nil {
+					return "", client.IgnoreNotFound(err)
+				}
+				if !deployment.DeletionTimestamp.IsZero() {
+					deployment.Finalizers = nil
+					return "", k8sClient.Update(ctx, deployment)
+				}
+				return deployment.UID, nil
+			}).ShouldNot(Or(BeEmpty(), Equal(legacy.UID)))
+
+			By("Checking that the deployment is recreated and the old tanks are kept")
+			Expect(deployment.Spec.Selector.MatchLabels).To(HaveKeyWithValue(controller.AppInstanceKey, aquarium.Name))
+			Expect(metav1.IsControlledBy(deployment, aquarium)).To(BeTrue())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(replicaSet), replicaSet)).To(Succeed())
+			Expect(replicaSet.OwnerReferences).To(ContainElement(HaveField("UID", aquarium.UID)))
+			Expect(replicaSet.Labels).NotTo(HaveKey(controller.AppKey))
+			Consistently(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), pod)
+			}).Should(Succeed())
+		})
+
+		It("should keep the old tanks away from the legacy deployments of other aquaria", func() {
+			ctx := context.Background()
+
+			legacyLabels := map[string]string{controller.AppKey: controller.AquariumValue}
+			template := corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: legacyLabels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: controller.TankContainerName, Image: "busybox"}},
+				},
+			}
+			newLegacyDeployment := func(name string) *appsv1.Deployment {
+				return &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: AquariumNamespace,
+						Labels:    legacyLabels,
+					},
+					Spec: appsv1.DeploymentSpec{
+						Selector: &metav1.LabelSelector{MatchLabels: legacyLabels},
+						Template: template,
+					},
+				}
+			}
+
+			By("Creating two aquaria the way older operators did")
+			east := newLegacyDeployment("legacy-east")
+			Expect(k8sClient.Create(ctx, east)).Should(Succeed())
+			west := newLegacyDeployment("legacy-west")
+			Expect(k8sClient.Create(ctx, west)).Should(Succeed())
+
+			replicaSet := &appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "legacy-east-6b8f9",
+					Namespace:       AquariumNamespace,
+					Labels:          legacyLabels,
+					OwnerReferences: []metav1.OwnerReference{controllerReference(east, "Deployment")},
+				},
+				Spec: appsv1.ReplicaSetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: legacyLabels},
+					Template: template,
+				},
+			}
+			Expect(k8sClient.Create(ctx, replicaSet)).Should(Succeed())
+
+			// The west aquarium is paused, so its legacy deployment is kept.
+			westAquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        west.Name,
+					Namespace:   AquariumNamespace,
+					Annotations: map[string]string{controller.PausedAnnotation: "true"},
+				},
+				Spec: funv1alpha1.AquariumSpec{NumTanks: 1, Location: "Atlanta"},
+			}
+			Expect(k8sClient.Create(ctx, westAquarium)).Should(Succeed())
+			eastAquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      east.Name,
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{NumTanks: 1, Location: "Atlanta"},
+			}
+			Expect(k8sClient.Create(ctx, eastAquarium)).Should(Succeed())
+
+			By("Migrating the east aquarium")
+			deployment := &appsv1.Deployment{}
+			Eventually(ctx, func() (types.UID, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(east), deployment); err != nil {
+					return "", client.IgnoreNotFound(err)
+				}
+				if !deployment.DeletionTimestamp.IsZero() {
+					deployment.Finalizers = nil
+					return "", k8sClient.Update(ctx, deployment)
+				}
+				return deployment.UID, nil
+			}).ShouldNot(Or(BeEmpty(), Equal(east.UID)))
+
+			By("Checking that the west deployment can't claim the old east tanks")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(west), west)).To(Succeed())
+			westSelector, err := metav1.LabelSelectorAsSelector(west.Spec.Selector)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(replicaSet), replicaSet)).To(Succeed())
+			Expect(replicaSet.Labels).To(HaveKeyWithValue(controller.LegacyTanksKey, string(eastAquarium.UID)))
+			Expect(westSelector.Matches(labels.Set(replicaSet.Labels))).To(BeFalse())
+		})
+
+		It("should leave deployments it doesn't own alone", func() {
+			ctx := context.Background()
+
+			otherLabels := map[string]string{"app": "gift-shop"}
+			other := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "gift-shop",
+					Namespace: AquariumNamespace,
+					Labels:    otherLabels,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: otherLabels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: otherLabels},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "shop", Image: "busybox"}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, other)).Should(Succeed())
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      other.Name,
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			Eventually(ctx, func() (*metav1.Condition, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return apimeta.FindStatusCondition(aquarium.Status.Conditions, controller.ReconcileError), err
+			}).Should(And(
+				HaveField("Status", metav1.ConditionTrue),
+				HaveField("Message", ContainSubstring("not owned by the aquarium")),
+			))
+
+			deployment := &appsv1.Deployment{}
+			Consistently(ctx, func() (*appsv1.Deployment, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(other), deployment)
+				return deployment, err
+			}).Should(And(
+				HaveField("UID", other.UID),
+				HaveField("DeletionTimestamp", BeNil()),
+				HaveField("Spec.Selector.MatchLabels", Equal(otherLabels)),
+			))
+		})
+	})
+
+	Context("When the deployment is changed by hand", func() {
+		It("should report the drift and revert it when enforced", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "drifting-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks:    1,
+					Location:    "Atlanta",
+					DriftPolicy: funv1alpha1.DriftReport,
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			deployment := &appsv1.Deployment{}
+			Eventually(ctx, func() (map[string]string, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+				return deployment.Annotations, err
+			}).Should(HaveKey(controller.DesiredStateAnnotation))
+
+			drifted := func() (*metav1.Condition, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium); err != nil {
+					return nil, err
+				}
+				return apimeta.FindStatusCondition(aquarium.Status.Conditions, controller.Drifted), nil
+			}
+			image := func() (string, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+				return deployment.Spec.Template.Spec.Containers[0].Image, err
+			}
+			Eventually(ctx, drifted).Should(HaveField("Status", metav1.ConditionFalse))
+
+			By("Changing the tank image by hand")
+			deployment.Spec.Template.Spec.Containers[0].Image = "busybox:1.36"
+			Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+
+			By("Checking that the drift is reported and kept")
+			Eventually(ctx, drifted).Should(And(
+				HaveField("Status", metav1.ConditionTrue),
+				HaveField("Reason", controller.DriftDetected),
+				HaveField("Message", ContainSubstring("spec.template.spec.containers[0].image")),
+			))
+			Consistently(ctx, image).Should(Equal("busybox:1.36"))
+			Eventually(ctx, eventReasons(ctx, aquarium.Name)).Should(ContainElement(controller.DeploymentDrifted))
+
+			By("Enforcing the aquarium on the deployment")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Spec.DriftPolicy = funv1alpha1.DriftEnforce
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+			Eventually(ctx, image).Should(Equal(TankImage))
+			Eventually(ctx, drifted).Should(HaveField("Reason", controller.NoDrift))
+		})
+	})
+
+	Context("When the tank template changes", func() {
+		It("should hold it back in canary tanks until it is promoted", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "canary-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 3,
+					Location: "Atlanta",
+					Rollout:  funv1alpha1.RolloutSpec{Strategy: funv1alpha1.RolloutCanary},
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			deployment := &appsv1.Deployment{}
+			Eventually(ctx, func() (map[string]string, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+				return deployment.Annotations, err
+			}).Should(HaveKey(controller.TemplateHashAnnotation))
+
+			By("Changing the tank image")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Spec.Tank = funv1alpha1.TankTemplate{Image: "busybox:1.36", Command: []string{"sleep", "10000"}}
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+
+			By("Checking that only the canary runs the new image")
+			canary := &appsv1.Deployment{}
+			canaryKey := types.NamespacedName{Name: "canary-aquarium-canary", Namespace: AquariumNamespace}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, canaryKey, canary)
+			}).Should(Succeed())
+			Expect(*canary.Spec.Replicas).To(BeEquivalentTo(1))
+			Expect(canary.Spec.Template.Spec.Containers[0].Image).To(Equal("busybox:1.36"))
+			Expect(canary.Spec.Template.Labels).To(HaveKeyWithValue(controller.TrackKey, controller.CanaryTrack))
+
+			Eventually(ctx, func() (*int32, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+				return deployment.Spec.Replicas, err
+			}).Should(HaveValue(BeEquivalentTo(2)))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal(TankImage))
+
+			By("Checking that the stable and canary tanks are selected apart")
+			stableSelector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stableSelector.Matches(labels.Set(canary.Spec.Template.Labels))).To(BeFalse())
+			canarySelector, err := metav1.LabelSelectorAsSelector(canary.Spec.Selector)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(canarySelector.Matches(labels.Set(deployment.Spec.Template.Labels))).To(BeFalse())
+
+			Eventually(ctx, func() (bool, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status.Rollout.AwaitingPromotion, err
+			}).Should(BeTrue())
+
+			By("Promoting the new image")
+			aquarium.Annotations = map[string]string{controller.PromoteAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+
+			Eventually(ctx, func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, canaryKey, canary))
+			}).Should(BeTrue())
+			Eventually(ctx, func() (string, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+				return deployment.Spec.Template.Spec.Containers[0].Image, err
+			}).Should(Equal("busybox:1.36"))
+			Expect(*deployment.Spec.Replicas).To(BeEquivalentTo(3))
+			Eventually(ctx, func() (map[string]string, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Annotations, err
+			}).ShouldNot(HaveKey(controller.PromoteAnnotation))
+			Eventually(ctx, eventReasons(ctx, aquarium.Name)).Should(ContainElement(controller.CanaryPromoted))
+		})
+
+		It("should recreate the tanks when asked to", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "recreated-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			deployment := &appsv1.Deployment{}
+			Eventually(ctx, func() (appsv1.DeploymentStrategyType, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+				return deployment.Spec.Strategy.Type, err
+			}).Should(Equal(appsv1.RollingUpdateDeploymentStrategyType))
+
+			By("Switching to Recreate")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Spec.Rollout.Strategy = funv1alpha1.RolloutRecreate
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+
+			Eventually(ctx, func() (appsv1.DeploymentStrategy, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+				return deployment.Spec.Strategy, err
+			}).Should(Equal(appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}))
+		})
+	})
+
+	Context("When the tanks may be disrupted", func() {
+		It("should keep enough of them running", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "protected-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 3,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			By("Checking that every tank but one is protected")
+			budget := &policyv1.PodDisruptionBudget{}
+			minAvailable := func() (*intstr.IntOrString, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), budget)
+				return budget.Spec.MinAvailable, err
+			}
+			Eventually(ctx, minAvailable).Should(HaveValue(Equal(intstr.FromInt(2))))
+			Expect(budget.Spec.Selector.MatchLabels).To(HaveKeyWithValue(controller.AppInstanceKey, aquarium.Name))
+			Expect(metav1.IsControlledBy(budget, aquarium)).To(BeTrue())
+
+			Eventually(ctx, func() (*metav1.Condition, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return apimeta.FindStatusCondition(aquarium.Status.Conditions, controller.DisruptionProtected), err
+			}).Should(And(
+				HaveField("Status", metav1.ConditionTrue),
+				HaveField("Reason", controller.TanksProtected),
+			))
+
+			By("Asking for more tanks than the aquarium runs")
+			aquarium.Spec.Disruption.MinAvailableTanks = pointer.Int32(5)
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+			Consistently(ctx, minAvailable).Should(HaveValue(Equal(intstr.FromInt(2))))
+
+			By("Asking for fewer tanks than the default")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Spec.Disruption.MinAvailableTanks = pointer.Int32(1)
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+			Eventually(ctx, minAvailable).Should(HaveValue(Equal(intstr.FromInt(1))))
+		})
+	})
+
+	Context("When the aquarium is exposed to visitors", func() {
+		It("should serve the tanks through a service and an ingress", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "exposed-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+					Exposure: &funv1alpha1.ExposureSpec{
+						Port:     8080,
+						Route:    funv1alpha1.RouteIngress,
+						Hostname: "bay.aquarium.example",
+						Path:     "/tanks",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			By("Checking that the tanks serve on the port")
+			deployment := &appsv1.Deployment{}
+			Eventually(ctx, func() ([]corev1.ContainerPort, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+				if err != nil {
+					return nil, err
+				}
+				return deployment.Spec.Template.Spec.Containers[0].Ports, nil
+			}).Should(ContainElement(HaveField("ContainerPort", BeEquivalentTo(8080))))
+
+			By("Checking the service and the ingress")
+			service := &corev1.Service{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), service)
+			}).Should(Succeed())
+			Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+			Expect(service.Spec.Selector).To(HaveKeyWithValue(controller.AppInstanceKey, aquarium.Name))
+			Expect(metav1.IsControlledBy(service, aquarium)).To(BeTrue())
+
+			ingress := &networkingv1.Ingress{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), ingress)
+			}).Should(Succeed())
+			Expect(ingress.Spec.Rules).To(ConsistOf(HaveField("Host", "bay.aquarium.example")))
+			Expect(ingress.Spec.Rules[0].HTTP.Paths).To(ConsistOf(HaveField("Path", "/tanks")))
+
+			Eventually(ctx, func() (string, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status.URL, err
+			}).Should(Equal("http://bay.aquarium.example/tanks"))
+
+			By("Taking the aquarium off display")
+			aquarium.Spec.Exposure = nil
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+			Eventually(ctx, func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), ingress))
+			}).Should(BeTrue())
+			Eventually(ctx, func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), service))
+			}).Should(BeTrue())
+			Eventually(ctx, func() (string, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status.URL, err
+			}).Should(BeEmpty())
+		})
+	})
+
+	Context("When the tanks move to a StatefulSet", func() {
+		It("should give them storage and retire the deployment once they are ready", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "stateful-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			deployment := &appsv1.Deployment{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+			}).Should(Succeed())
+
+			By("Switching to a StatefulSet with storage")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Spec.WorkloadKind = funv1alpha1.WorkloadStatefulSet
+			aquarium.Spec.Storage = &funv1alpha1.StorageSpec{Size: resource.MustParse("1Gi")}
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+
+			statefulSet := &appsv1.StatefulSet{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), statefulSet)
+			}).Should(Succeed())
+			Expect(statefulSet.Spec.ServiceName).To(Equal("stateful-aquarium-tanks"))
+			Expect(statefulSet.Spec.VolumeClaimTemplates).To(ConsistOf(HaveField("Name", controller.TankVolumeName)))
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(And(
+				HaveField("Name", controller.TankVolumeName),
+				HaveField("MountPath", funv1alpha1.DefaultTankMountPath),
+			)))
+
+			headless := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      statefulSet.Spec.ServiceName,
+				Namespace: AquariumNamespace,
+			}, headless)).To(Succeed())
+			Expect(headless.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+
+			By("Checking that the deployment keeps running until the new tanks are ready")
+			Consistently(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+			}).Should(Succeed())
+
+			By("Making the new tanks ready")
+			Eventually(ctx, func() error {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), statefulSet); err != nil {
+					return err
+				}
+				statefulSet.Status.ObservedGeneration = statefulSet.Generation
+				statefulSet.Status.Replicas = 1
+				statefulSet.Status.ReadyReplicas = 1
+				statefulSet.Status.AvailableReplicas = 1
+				statefulSet.Status.UpdatedReplicas = 1
+				return k8sClient.Status().Update(ctx, statefulSet)
+			}).Should(Succeed())
+
+			Eventually(ctx, func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deployment)
+				return apierrors.IsNotFound(err) || !deployment.DeletionTimestamp.IsZero()
+			}).Should(BeTrue())
+			Eventually(ctx, func() (int32, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status.NumTanksReady, err
+			}).Should(BeEquivalentTo(1))
+			Expect(apimeta.FindStatusCondition(aquarium.Status.Conditions, controller.Drifted)).To(And(
+				HaveField("Status", metav1.ConditionUnknown),
+				HaveField("Reason", controller.DriftNotReported),
+			))
+		})
+	})
+
+	Context("When the tanks run in pods", func() {
+		It("should report on every tank", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tank-status-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			By("Starting the pod of a tank")
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tank-status-aquarium-0",
+					Namespace: AquariumNamespace,
+					Labels: map[string]string{
+						controller.AppNameKey:     controller.AquariumAppName,
+						controller.AppInstanceKey: aquarium.Name,
+						controller.AquariumUIDKey: string(aquarium.UID),
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName:   "reef-node",
+					Containers: []corev1.Container{{Name: controller.TankContainerName, Image: "busybox"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pod)).Should(Succeed())
+			pod.Status = corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}},
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:         controller.TankContainerName,
+					RestartCount: 3,
+					LastTerminationState: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"},
+					},
+				}},
+			}
+			Expect(k8sClient.Status().Update(ctx, pod)).Should(Succeed())
+
+			By("Checking the status of the tank")
+			Eventually(ctx, func() ([]funv1alpha1.TankStatus, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status.Tanks, err
+			}).Should(ConsistOf(funv1alpha1.TankStatus{
+				Name:                  pod.Name,
+				Node:                  "reef-node",
+				Phase:                 corev1.PodRunning,
+				RestartCount:          3,
+				LastTerminationReason: "OOMKilled",
+			}))
+
+			By("Removing the pod of the tank")
+			Expect(k8sClient.Delete(ctx, pod, client.GracePeriodSeconds(0))).Should(Succeed())
+			Eventually(ctx, func() ([]funv1alpha1.TankStatus, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Status.Tanks, err
+			}).Should(BeEmpty())
+		})
+	})
+
+	Context("When an exhibit spans locations", func() {
+		It("should build an aquarium at every location", func() {
+			ctx := context.Background()
+
+			exhibit := &funv1alpha1.Exhibit{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "kelp-forest",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.ExhibitSpec{
+					Template: funv1alpha1.AquariumTemplate{
+						Labels: map[string]string{"exhibit": "kelp-forest"},
+						Spec:   funv1alpha1.AquariumSpec{NumTanks: 1},
+					},
+					Locations: []funv1alpha1.ExhibitLocation{
+						{Location: "Atlanta"},
+						{Location: "Monterey Bay", NumTanks: pointer.Int32(2)},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, exhibit)).Should(Succeed())
+
+			By("Checking that the aquaria are built from the template")
+			atlanta := &funv1alpha1.Aquarium{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{
+					Name:      "kelp-forest-atlanta",
+					Namespace: AquariumNamespace,
+				}, atlanta)
+			}).Should(Succeed())
+			Expect(atlanta.Spec.Location).To(Equal("Atlanta"))
+			Expect(atlanta.Spec.NumTanks).To(BeEquivalentTo(1))
+			Expect(atlanta.Labels).To(HaveKeyWithValue(controller.ExhibitKey, exhibit.Name))
+			Expect(atlanta.Labels).To(HaveKeyWithValue("exhibit", "kelp-forest"))
+			Expect(metav1.IsControlledBy(atlanta, exhibit)).To(BeTrue())
+
+			monterey := &funv1alpha1.Aquarium{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{
+					Name:      "kelp-forest-monterey-bay",
+					Namespace: AquariumNamespace,
+				}, monterey)
+			}).Should(Succeed())
+			Expect(monterey.Spec.Location).To(Equal("Monterey Bay"))
+			Expect(monterey.Spec.NumTanks).To(BeEquivalentTo(2))
+
+			By("Checking that the exhibit reports every location")
+			Eventually(ctx, func() ([]funv1alpha1.ExhibitLocationStatus, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(exhibit), exhibit)
+				return exhibit.Status.Locations, err
+			}).Should(ConsistOf(
+				HaveField("Aquarium", atlanta.Name),
+				HaveField("Aquarium", monterey.Name),
+			))
+			Expect(exhibit.Status.FishHealth).NotTo(Equal(funv1alpha1.Healthy))
+			Expect(apimeta.IsStatusConditionFalse(exhibit.Status.Conditions, controller.Ready)).To(BeTrue())
+
+			By("Dropping a location")
+			exhibit.Spec.Locations = exhibit.Spec.Locations[:1]
+			Expect(k8sClient.Update(ctx, exhibit)).To(Succeed())
+			Eventually(ctx, func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(monterey), monterey)
+				return apierrors.IsNotFound(err) || !monterey.DeletionTimestamp.IsZero()
+			}).Should(BeTrue())
+			Consistently(ctx, func() (*metav1.Time, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(atlanta), atlanta)
+				return atlanta.DeletionTimestamp, err
+			}).Should(BeNil())
+		})
+
+		It("should refuse locations without an aquarium of their own", func() {
+			ctx := context.Background()
+
+			By("Building an aquarium by hand where the exhibit will go")
+			existing := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "reef-walk-atlanta",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 3,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, existing)).Should(Succeed())
+
+			exhibit := &funv1alpha1.Exhibit{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "reef-walk",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.ExhibitSpec{
+					Template: funv1alpha1.AquariumTemplate{
+						Spec: funv1alpha1.AquariumSpec{NumTanks: 1},
+					},
+					Locations: []funv1alpha1.ExhibitLocation{
+						{Location: "Pier 39"},
+						{Location: "pier-39"},
+						{Location: "???"},
+						{Location: "Atlanta"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, exhibit)).Should(Succeed())
+
+			By("Checking that the conflicts are reported")
+			Eventually(ctx, func() (*metav1.Condition, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(exhibit), exhibit)
+				return apimeta.FindStatusCondition(exhibit.Status.Conditions, controller.Ready), err
+			}).Should(And(
+				HaveField("Status", metav1.ConditionFalse),
+				HaveField("Reason", controller.ReconcileFailed),
+				HaveField("Message", ContainSubstring(`"Pier 39" and "pier-39" both name aquarium reef-walk-pier-39`)),
+				HaveField("Message", ContainSubstring(`location "???" can't name aquarium`)),
+				HaveField("Message", ContainSubstring("reef-walk-atlanta already exists and is not owned by the exhibit")),
+			))
+
+			By("Checking that the first location keeps its aquarium")
+			pier := &funv1alpha1.Aquarium{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      "reef-walk-pier-39",
+				Namespace: AquariumNamespace,
+			}, pier)).To(Succeed())
+			Expect(pier.Spec.Location).To(Equal("Pier 39"))
+
+			By("Checking that the aquarium built by hand is left alone")
+			Consistently(ctx, func() (*funv1alpha1.Aquarium, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(existing), existing)
+				return existing, err
+			}).Should(And(
+				HaveField("OwnerReferences", BeEmpty()),
+				HaveField("Spec.NumTanks", BeEquivalentTo(3)),
+			))
+		})
+	})
+
+	Context("When the aquarium is deleted", func() {
+		It("should drain the tanks before letting it go", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "closing-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 2,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+
+			By("Checking that the aquarium gets the teardown finalizer")
+			Eventually(ctx, func() ([]string, error) {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium); err != nil {
+					return nil, err
+				}
+
+				return aquarium.Finalizers, nil
+			}).Should(ContainElement(controller.AquariumFinalizer))
+
+			deploy := &appsv1.Deployment{}
+			Eventually(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), deploy)
+			}).Should(Succeed())
+
+			By("Deleting the aquarium")
+			Expect(k8sClient.Delete(ctx, aquarium)).To(Succeed())
+
+			Eventually(ctx, func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return apierrors.IsNotFound(err)
+			}).Should(BeTrue())
+
+			By("Checking that the tanks were scaled to zero")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(deploy), deploy)).To(Succeed())
+			Expect(*deploy.Spec.Replicas).To(BeZero())
+
+			By("Checking that the aquarium was closed")
+			Eventually(ctx, eventReasons(ctx, aquarium.Name)).Should(ContainElement(controller.Closed))
+
+			By("Checking that the aquarium's metrics were removed")
+			Eventually(func() ([]float64, error) {
+				return metricValues("aquarium_tanks_desired", map[string]string{
+					"namespace": AquariumNamespace,
+					"aquarium":  aquarium.Name,
+				})
+			}).Should(BeEmpty())
+		})
+
+		It("should wait for the tanks of legacy replica sets", func() {
+			ctx := context.Background()
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "closing-legacy-aquarium",
+					Namespace: AquariumNamespace,
+				},
+				Spec: funv1alpha1.AquariumSpec{
+					NumTanks: 1,
+					Location: "Atlanta",
+				},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).Should(Succeed())
+			Eventually(ctx, func() ([]string, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return aquarium.Finalizers, err
+			}).Should(ContainElement(controller.AquariumFinalizer))
+
+			By("Leaving a replica set adopted by a migration running")
+			legacyLabels := map[string]string{controller.AppKey: controller.AquariumValue}
+			template := corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: legacyLabels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: controller.TankContainerName, Image: "busybox"}},
+				},
+			}
+			adopted := metav1.OwnerReference{
+				APIVersion: funv1alpha1.GroupVersion.String(),
+				Kind:       "Aquarium",
+				Name:       aquarium.Name,
+				UID:        aquarium.UID,
+			}
+			replicaSet := &appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "closing-legacy-aquarium-7f6d4",
+					Namespace:       AquariumNamespace,
+					Labels:          legacyLabels,
+					OwnerReferences: []metav1.OwnerReference{adopted},
+				},
+				Spec: appsv1.ReplicaSetSpec{
+					Replicas: pointer.Int32(1),
+					Selector: &metav1.LabelSelector{MatchLabels: legacyLabels},
+					Template: template,
+				},
+			}
+			Expect(k8sClient.Create(ctx, replicaSet)).Should(Succeed())
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "closing-legacy-aquarium-7f6d4-q9w3z",
+					Namespace:       AquariumNamespace,
+					Labels:          legacyLabels,
+					OwnerReferences: []metav1.OwnerReference{controllerReference(replicaSet, "ReplicaSet")},
+				},
+				Spec: template.Spec,
+			}
+			Expect(k8sClient.Create(ctx, pod)).Should(Succeed())
+
+			By("Deleting the aquarium")
+			Expect(k8sClient.Delete(ctx, aquarium)).To(Succeed())
+			Eventually(ctx, func() (*int32, error) {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(replicaSet), replicaSet)
+				return replicaSet.Spec.Replicas, err
+			}).Should(HaveValue(BeZero()))
+			Consistently(ctx, func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+			}, "2s").Should(Succeed())
+
+			By("Letting it go once the legacy tanks are gone")
+			Expect(k8sClient.Delete(ctx, pod, client.GracePeriodSeconds(0))).To(Succeed())
+			Eventually(ctx, func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)
+				return apierrors.IsNotFound(err)
+			}).Should(BeTrue())
+		})
+	})
+})
+
+// eventReasons returns a function listing the reasons of the events recorded for an aquarium.
+func eventReasons(ctx context.Context, name string) func() ([]string, error) {
+	return func() ([]string, error) {
+		var events corev1.EventList
+		if err := k8sClient.List(ctx, &events, client.InNamespace(AquariumNamespace)); err != nil {
+			return nil, err
+		}
+
+		var reasons []string
+		for _, event := range events.Items {
+			if event.InvolvedObject.Kind == "Aquarium" && event.InvolvedObject.Name == name {
+				reasons = append(reasons, event.Reason)
+			}
+		}
+
+		return reasons, nil
+	}
+}
+
+// metricValues returns the values of the series of a metric whose labels include the given ones.
+func metricValues(name string, labels map[string]string) ([]float64, error) {
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	var values []float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+	series:
+		for _, metric := range family.GetMetric() {
+			matched := 0
+			for _, label := range metric.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok {
+					if value != label.GetValue() {
+						continue series
+					}
+					matched++
+				}
+			}
+			if matched != len(labels) {
+				continue
+			}
+
+			switch {
+			case metric.GetGauge() != nil:
+				values = append(values, metric.GetGauge().GetValue())
+			case metric.GetCounter() != nil:
+				values = append(values, metric.GetCounter().GetValue())
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// controllerReference returns a reference making an apps/v1 object of the given kind the controller of another.
+func controllerReference(owner client.Object, kind string) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: appsv1.SchemeGroupVersion.String(),
+		Kind:       kind,
+		Name:       owner.GetName(),
+		UID:        owner.GetUID(),
+		Controller: pointer.Bool(true),
+	}
+}
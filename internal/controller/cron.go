@@ -0,0 +1,16 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ParseCron parses a standard cron expression, interpreted in timeZone when it is set.
+func ParseCron(expression string, timeZone *string) (cron.Schedule, error) {
+	if timeZone != nil && *timeZone != "" {
+		expression = fmt.Sprintf("CRON_TZ=%s %s", *timeZone, expression)
+	}
+
+	return cron.ParseStandard(expression)
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// reconcileDisruptionBudget applies the PodDisruptionBudget that keeps enough
+// of the aquarium's tanks running through node drains, and reports on it in
+// the DisruptionProtected condition. tanks is the number of tanks the
+// aquarium runs.
+func (r *AquariumReconciler) reconcileDisruptionBudget(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	tanks int32,
+) error {
+	minAvailable := minAvailableTanks(aquarium, tanks)
+	if err := r.Patch(
+		ctx,
+		newPodDisruptionBudget(aquarium, minAvailable),
+		client.Apply,
+		client.ForceOwnership,
+		client.FieldOwner(AquariumOperator),
+	); err != nil {
+		setCondition(aquarium, DisruptionProtected, metav1.ConditionUnknown, ReconcileFailed, err.Error())
+		return fmt.Errorf("failed to apply pod disruption budget: %w", err)
+	}
+
+	if minAvailable == 0 {
+		setCondition(aquarium, DisruptionProtected, metav1.ConditionFalse, TanksUnprotected,
+			"Voluntary disruptions may take down every tank")
+		return nil
+	}
+
+	setCondition(aquarium, DisruptionProtected, metav1.ConditionTrue, TanksProtected,
+		fmt.Sprintf("At least %d of %d tanks keep running through voluntary disruptions", minAvailable, tanks))
+
+	return nil
+}
+
+// minAvailableTanks is the number of tanks voluntary disruptions must leave
+// running: spec.disruption.min_available_tanks, or every tank but one. It
+// never exceeds every tank but one, so node drains aren't blocked for good.
+func minAvailableTanks(aquarium *funv1alpha1.Aquarium, tanks int32) int32 {
+	minAvailable := tanks - 1
+	if configured := aquarium.Spec.Disruption.MinAvailableTanks; configured != nil && *configured < minAvailable {
+		minAvailable = *configured
+	}
+
+	if minAvailable < 0 {
+		minAvailable = 0
+	}
+
+	return minAvailable
+}
+
+func newPodDisruptionBudget(aquarium *funv1alpha1.Aquarium, minAvailable int32) *policyv1.PodDisruptionBudget {
+	tanks := intstr.FromInt(int(minAvailable))
+
+	return &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: policyv1.SchemeGroupVersion.String(),
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            aquarium.Name,
+			Namespace:       aquarium.Namespace,
+			Labels:          aquariumLabels(aquarium),
+			OwnerReferences: []metav1.OwnerReference{ownerReference(aquarium)},
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &tanks,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selectorLabels(aquarium),
+			},
+		},
+	}
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// maxDriftedFields caps how many changed fields are listed in drift reports.
+const maxDriftedFields = 5
+
+// setDesiredState annotates a desired Deployment with a hash of itself, so the
+// operator can tell its own changes of heart from changes made by others.
+func setDesiredState(deploy *appsv1.Deployment) error {
+	data, err := json.Marshal(deploy)
+	if err != nil {
+		return fmt.Errorf("failed to hash deployment: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	if deploy.Annotations == nil {
+		deploy.Annotations = map[string]string{}
+	}
+	deploy.Annotations[DesiredStateAnnotation] = hex.EncodeToString(sum[:8])
+
+	return nil
+}
+
+// reconcileDrift looks for changes made to the live Deployment outside the
+// operator, reports them in the Drifted condition, and returns whether the
+// desired Deployment should be applied. It always is when the operator wants
+// something new of the Deployment. Otherwise only Enforce reverts drift, and
+// a Deployment without drift is left as it is.
+func (r *AquariumReconciler) reconcileDrift(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	live, desired *appsv1.Deployment,
+) (bool, error) {
+	policy := aquarium.Spec.DriftPolicy
+	if policy == "" {
+		policy = funv1alpha1.DriftEnforce
+	}
+
+	if policy == funv1alpha1.DriftIgnore {
+		setCondition(aquarium, Drifted, metav1.ConditionUnknown, DriftIgnored,
+			"Changes made to the deployment outside the operator are ignored")
+	}
+
+	if live == nil || live.Annotations[DesiredStateAnnotation] != desired.Annotations[DesiredStateAnnotation] {
+		if policy != funv1alpha1.DriftIgnore {
+			setCondition(aquarium, Drifted, metav1.ConditionFalse, NoDrift, "The deployment is as the operator left it")
+		}
+		return true, nil
+	}
+
+	if policy == funv1alpha1.DriftIgnore {
+		return false, nil
+	}
+
+	fields, err := r.driftedFields(ctx, live, desired)
+	if err != nil {
+		return false, err
+	}
+
+	if len(fields) == 0 {
+		setCondition(aquarium, Drifted, metav1.ConditionFalse, NoDrift, "The deployment is as the operator left it")
+		return false, nil
+	}
+
+	summary := summarizeFields(fields)
+	if policy == funv1alpha1.DriftEnforce {
+		setCondition(aquarium, Drifted, metav1.ConditionTrue, DriftReverted,
+			fmt.Sprintf("Reverted changes made outside the operator to %s", summary))
+		r.events.Eventf(aquarium, corev1.EventTypeWarning, DeploymentDrifted,
+			"Deployment %s was changed outside the operator, reverting %s", live.Name, summary)
+		return true, nil
+	}
+
+	setCondition(aquarium, Drifted, metav1.ConditionTrue, DriftDetected,
+		fmt.Sprintf("Changes made outside the operator to %s are kept", summary))
+	r.events.Eventf(aquarium, corev1.EventTypeWarning, DeploymentDrifted,
+		"Deployment %s was changed outside the operator, keeping %s", live.Name, summary)
+
+	return false, nil
+}
+
+// driftedFields dry runs applying the desired Deployment and returns the
+// fields applying it would change. Those are the fields the operator owns
+// that were changed by someone else.
+func (r *AquariumReconciler) driftedFields(
+	ctx context.Context,
+	live, desired *appsv1.Deployment,
+) ([]string, error) {
+	applied := desired.DeepCopy()
+	if err := r.Patch(
+		ctx,
+		applied,
+		client.Apply,
+		client.ForceOwnership,
+		client.FieldOwner(AquariumOperator),
+		client.DryRunAll,
+	); err != nil {
+		return nil, fmt.Errorf("failed to dry run applying deployment: %w", err)
+	}
+
+	liveContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(live)
+	if err != nil {
+		return nil, err
+	}
+	appliedContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(applied)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	fields = append(fields, diffFields("metadata.labels", live.Labels, applied.Labels)...)
+	fields = append(fields, diffFields("metadata.annotations", live.Annotations, applied.Annotations)...)
+	fields = append(fields, diffFields("spec", liveContent["spec"], appliedContent["spec"])...)
+
+	return fields, nil
+}
+
+// diffFields returns the paths at which two decoded objects differ.
+func diffFields(path string, a, b interface{}) []string {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := map[string]bool{}
+		for key := range a {
+			keys[key] = true
+		}
+		for key := range b {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		var fields []string
+		for _, key := range sorted {
+			fields = append(fields, diffFields(path+"."+key, a[key], b[key])...)
+		}
+		return fields
+	case map[string]string:
+		b, ok := b.(map[string]string)
+		if !ok {
+			break
+		}
+
+		var fields []string
+		for key := range a {
+			if a[key] != b[key] {
+				fields = append(fields, fmt.Sprintf("%s[%s]", path, key))
+			}
+		}
+		for key := range b {
+			if _, ok := a[key]; !ok {
+				fields = append(fields, fmt.Sprintf("%s[%s]", path, key))
+			}
+		}
+		sort.Strings(fields)
+		return fields
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			break
+		}
+
+		var fields []string
+		for i := range a {
+			fields = append(fields, diffFields(fmt.Sprintf("%s[%d]", path, i), a[i], b[i])...)
+		}
+		return fields
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+
+	return []string{path}
+}
+
+// summarizeFields lists the first few changed fields.
+func summarizeFields(fields []string) string {
+	if len(fields) <= maxDriftedFields {
+		return strings.Join(fields, ", ")
+	}
+
+	return fmt.Sprintf("%s and %d more fields",
+		strings.Join(fields[:maxDriftedFields], ", "), len(fields)-maxDriftedFields)
+}
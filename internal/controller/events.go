@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// eventDedupWindow is how long an identical event is suppressed after it was recorded.
+const eventDedupWindow = 10 * time.Minute
+
+type eventKey struct {
+	uid       types.UID
+	eventType string
+	reason    string
+	message   string
+}
+
+// eventDeduper records events, dropping repeats of an identical event for the
+// same object within a window so a flapping aquarium doesn't spam the API server.
+type eventDeduper struct {
+	recorder record.EventRecorder
+	window   time.Duration
+
+	mu   sync.Mutex
+	seen map[eventKey]time.Time
+}
+
+func newEventDeduper(recorder record.EventRecorder, window time.Duration) *eventDeduper {
+	return &eventDeduper{
+		recorder: recorder,
+		window:   window,
+		seen:     map[eventKey]time.Time{},
+	}
+}
+
+// Eventf records an event unless an identical one was recorded for the object within the window.
+func (d *eventDeduper) Eventf(obj client.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	key := eventKey{uid: obj.GetUID(), eventType: eventType, reason: reason, message: message}
+	now := time.Now()
+
+	d.mu.Lock()
+	if last, ok := d.seen[key]; ok && now.Sub(last) < d.window {
+		d.mu.Unlock()
+		return
+	}
+	d.seen[key] = now
+	d.prune(now)
+	d.mu.Unlock()
+
+	d.recorder.Event(obj, eventType, reason, message)
+}
+
+// Forget drops everything remembered about an object.
+func (d *eventDeduper) Forget(uid types.UID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key := range d.seen {
+		if key.uid == uid {
+			delete(d.seen, key)
+		}
+	}
+}
+
+// prune drops events that fell out of the window. d.mu must be held.
+func (d *eventDeduper) prune(now time.Time) {
+	for key, last := range d.seen {
+		if now.Sub(last) >= d.window {
+			delete(d.seen, key)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// nonNameCharacters are the characters that can't be part of an object name.
+var nonNameCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// ExhibitReconciler reconciles a Exhibit object
+type ExhibitReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=exhibits,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=exhibits/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=exhibits/finalizers,verbs=update
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=aquaria,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile builds an aquarium at every location of an exhibit, tears down
+// the aquaria of locations that were dropped and rolls up their fish health.
+func (r *ExhibitReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithValues("exhibit", req.Name, "ns", req.Namespace)
+
+	var exhibit funv1alpha1.Exhibit
+	if err := r.Get(ctx, req.NamespacedName, &exhibit); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// The aquaria are garbage collected with the exhibit.
+	if !exhibit.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	// Locations without a name of their own get no aquarium. Retrying won't
+	// help them, only a change of the exhibit does.
+	names, namesErr := exhibitAquariumNames(&exhibit)
+
+	var errs []error
+	aquaria := make([]*funv1alpha1.Aquarium, 0, len(exhibit.Spec.Locations))
+	for i, location := range exhibit.Spec.Locations {
+		var aquarium *funv1alpha1.Aquarium
+		if names[i] != "" {
+			var err error
+			if aquarium, err = r.applyExhibitAquarium(ctx, &exhibit, location, names[i]); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		aquaria = append(aquaria, aquarium)
+	}
+
+	if err := r.deleteDroppedAquaria(ctx, &exhibit, names); err != nil {
+		errs = append(errs, err)
+	}
+
+	reconcileErr := errors.Join(errs...)
+	if reconcileErr != nil {
+		log.Error(reconcileErr, "failed to reconcile the aquaria of the exhibit")
+	}
+
+	rollUpExhibitStatus(&exhibit, names, aquaria, errors.Join(namesErr, reconcileErr))
+	if err := r.Status().Update(ctx, &exhibit); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update exhibit status: %w", err)
+	}
+
+	return ctrl.Result{}, reconcileErr
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ExhibitReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&funv1alpha1.Exhibit{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&funv1alpha1.Aquarium{}).
+		Complete(r)
+}
+
+// applyExhibitAquarium applies the aquarium of an exhibit at a location. An
+// aquarium of that name the exhibit doesn't control is left alone.
+func (r *ExhibitReconciler) applyExhibitAquarium(
+	ctx context.Context,
+	exhibit *funv1alpha1.Exhibit,
+	location funv1alpha1.ExhibitLocation,
+	name string,
+) (*funv1alpha1.Aquarium, error) {
+	var live funv1alpha1.Aquarium
+	err := r.Get(ctx, client.ObjectKey{Namespace: exhibit.Namespace, Name: name}, &live)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+	if err == nil && !metav1.IsControlledBy(&live, exhibit) {
+		return nil, fmt.Errorf("aquarium %s already exists and is not owned by the exhibit", name)
+	}
+
+	aquarium := newExhibitAquarium(exhibit, location, name)
+	if err := r.Patch(
+		ctx,
+		aquarium,
+		client.Apply,
+		client.ForceOwnership,
+		client.FieldOwner(AquariumOperator),
+	); err != nil {
+		return nil, fmt.Errorf("failed to apply aquarium %s: %w", name, err)
+	}
+
+	return aquarium, nil
+}
+
+// deleteDroppedAquaria deletes the aquaria of an exhibit at locations it is no
+// longer shown at. names are the names of the aquaria of its locations.
+func (r *ExhibitReconciler) deleteDroppedAquaria(
+	ctx context.Context,
+	exhibit *funv1alpha1.Exhibit,
+	names []string,
+) error {
+	var aquaria funv1alpha1.AquariumList
+	if err := r.List(
+		ctx,
+		&aquaria,
+		client.InNamespace(exhibit.Namespace),
+		client.MatchingLabels{ExhibitKey: exhibit.Name},
+	); err != nil {
+		return fmt.Errorf("failed to list aquaria of exhibit: %w", err)
+	}
+
+	shown := map[string]bool{}
+	for _, name := range names {
+		shown[name] = true
+	}
+
+	for i := range aquaria.Items {
+		aquarium := &aquaria.Items[i]
+		if shown[aquarium.Name] || !metav1.IsControlledBy(aquarium, exhibit) || !aquarium.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		log.FromContext(ctx).Info("deleting aquarium of dropped location", "aquarium", aquarium.Name)
+		if err := r.Delete(ctx, aquarium); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete aquarium %s: %w", aquarium.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// newExhibitAquarium builds the aquarium of an exhibit at a location.
+func newExhibitAquarium(
+	exhibit *funv1alpha1.Exhibit,
+	location funv1alpha1.ExhibitLocation,
+	name string,
+) *funv1alpha1.Aquarium {
+	template := exhibit.Spec.Template.DeepCopy()
+
+	labels := template.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[ExhibitKey] = exhibit.Name
+
+	aquarium := &funv1alpha1.Aquarium{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: funv1alpha1.GroupVersion.String(),
+			Kind:       "Aquarium",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   exhibit.Namespace,
+			Labels:      labels,
+			Annotations: template.Annotations,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion:         funv1alpha1.GroupVersion.String(),
+				Kind:               "Exhibit",
+				Name:               exhibit.Name,
+				UID:                exhibit.UID,
+				Controller:         pointer.Bool(true),
+				BlockOwnerDeletion: pointer.Bool(true),
+			}},
+		},
+		Spec: template.Spec,
+	}
+
+	aquarium.Spec.Location = location.Location
+	if location.NumTanks != nil {
+		aquarium.Spec.NumTanks = *location.NumTanks
+	}
+
+	return aquarium
+}
+
+// exhibitAquariumName names the aquarium of an exhibit at a location.
+func exhibitAquariumName(exhibit *funv1alpha1.Exhibit, location string) string {
+	return fmt.Sprintf("%s-%s", exhibit.Name, locationName(location))
+}
+
+// exhibitAquariumNames names the aquaria of the locations of an exhibit, in
+// order. Locations whose name isn't valid, or is taken by an earlier location,
+// are left unnamed and reported in the returned error.
+func exhibitAquariumNames(exhibit *funv1alpha1.Exhibit) ([]string, error) {
+	var errs []error
+	names := make([]string, len(exhibit.Spec.Locations))
+	taken := map[string]string{}
+	for i, location := range exhibit.Spec.Locations {
+		name := exhibitAquariumName(exhibit, location.Location)
+		var problems []string
+		if strings.HasSuffix(name, "-") {
+			problems = append(problems, "the location has no letters or digits")
+		} else {
+			problems = validation.IsDNS1123Label(name)
+		}
+		if len(problems) > 0 {
+			errs = append(errs, fmt.Errorf("location %q can't name aquarium %q: %s",
+				location.Location, name, strings.Join(problems, ", ")))
+			continue
+		}
+		if other, ok := taken[name]; ok {
+			errs = append(errs, fmt.Errorf("locations %q and %q both name aquarium %s",
+				other, location.Location, name))
+			continue
+		}
+
+		taken[name] = location.Location
+		names[i] = name
+	}
+
+	return names, errors.Join(errs...)
+}
+
+// rollUpExhibitStatus sums up the aquaria of an exhibit, in the order of its
+// locations. Aquaria that couldn't be applied are nil, and the names of aquaria
+// that couldn't be named are empty. The fish of an exhibit are healthy when they
+// are healthy everywhere, and unhealthy when they are healthy nowhere.
+func rollUpExhibitStatus(
+	exhibit *funv1alpha1.Exhibit,
+	names []string,
+	aquaria []*funv1alpha1.Aquarium,
+	reconcileErr error,
+) {
+	status := &exhibit.Status
+	status.Locations = nil
+	status.HealthyLocations = 0
+
+	var healthy, kinda, unknown int
+	for i, location := range exhibit.Spec.Locations {
+		locationStatus := funv1alpha1.ExhibitLocationStatus{
+			Location:   location.Location,
+			Aquarium:   names[i],
+			FishHealth: funv1alpha1.Unknown,
+		}
+		if aquarium := aquaria[i]; aquarium != nil {
+			locationStatus.NumTanksReady = aquarium.Status.NumTanksReady
+			if aquarium.Status.FishHealth != "" {
+				locationStatus.FishHealth = aquarium.Status.FishHealth
+			}
+		}
+
+		switch locationStatus.FishHealth {
+		case funv1alpha1.Healthy:
+			healthy++
+		case funv1alpha1.KindOfHealthy:
+			kinda++
+		case funv1alpha1.Unknown:
+			unknown++
+		}
+		status.Locations = append(status.Locations, locationStatus)
+	}
+	status.HealthyLocations = int32(healthy)
+
+	total := len(exhibit.Spec.Locations)
+	switch {
+	case unknown == total:
+		status.FishHealth = funv1alpha1.Unknown
+	case healthy == total:
+		status.FishHealth = funv1alpha1.Healthy
+	case healthy+kinda > 0:
+		status.FishHealth = funv1alpha1.KindOfHealthy
+	default:
+		status.FishHealth = funv1alpha1.Unhealthy
+	}
+
+	readiness := metav1.Condition{
+		Type:               Ready,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: exhibit.Generation,
+		Reason:             AquariaHealthy,
+		Message:            fmt.Sprintf("The fish are healthy at all %d locations", total),
+	}
+	if healthy < total {
+		readiness.Status = metav1.ConditionFalse
+		readiness.Reason = AquariaNotHealthy
+		readiness.Message = fmt.Sprintf("The fish are healthy at %d of %d locations", healthy, total)
+	}
+	if reconcileErr != nil {
+		readiness.Status = metav1.ConditionFalse
+		readiness.Reason = ReconcileFailed
+		readiness.Message = reconcileErr.Error()
+	} else {
+		status.ObservedGeneration = exhibit.Generation
+	}
+	apimeta.SetStatusCondition(&status.Conditions, readiness)
+}
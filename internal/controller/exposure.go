@@ -0,0 +1,264 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// servicePort is the port the Service of the tanks serves visitors on.
+const servicePort = 80
+
+// httpRouteGVK is the Gateway API HTTPRoute. It is handled unstructured so the
+// operator runs in clusters without the Gateway API.
+var httpRouteGVK = schema.GroupVersionKind{
+	Group:   "gateway.networking.k8s.io",
+	Version: "v1beta1",
+	Kind:    "HTTPRoute",
+}
+
+// reconcileExposure applies the Service of an exposed aquarium and the route
+// to it, and removes whatever the aquarium no longer asks for. It returns the
+// URL visitors reach the aquarium at, empty while it has none.
+func (r *AquariumReconciler) reconcileExposure(ctx context.Context, aquarium *funv1alpha1.Aquarium) (string, error) {
+	exposure := aquarium.Spec.Exposure
+	if exposure == nil {
+		return "", r.deleteOwned(ctx, aquarium, &corev1.Service{}, &networkingv1.Ingress{}, emptyHTTPRoute())
+	}
+
+	service := newService(aquarium)
+	if err := r.Patch(
+		ctx,
+		service,
+		client.Apply,
+		client.ForceOwnership,
+		client.FieldOwner(AquariumOperator),
+	); err != nil {
+		return "", fmt.Errorf("failed to apply service: %w", err)
+	}
+
+	var route client.Object
+	stale := []client.Object{&networkingv1.Ingress{}, emptyHTTPRoute()}
+	switch exposure.Route {
+	case funv1alpha1.RouteIngress:
+		route, stale = newIngress(aquarium), stale[1:]
+	case funv1alpha1.RouteHTTPRoute:
+		route, stale = newHTTPRoute(aquarium), stale[:1]
+	}
+
+	if route != nil {
+		if err := r.Patch(
+			ctx,
+			route,
+			client.Apply,
+			client.ForceOwnership,
+			client.FieldOwner(AquariumOperator),
+		); err != nil {
+			return "", fmt.Errorf("failed to apply %s: %w", route.GetObjectKind().GroupVersionKind().Kind, err)
+		}
+	}
+
+	if err := r.deleteOwned(ctx, aquarium, stale...); err != nil {
+		return "", err
+	}
+
+	return exposureURL(exposure, service), nil
+}
+
+// deleteOwned deletes the given objects of an aquarium, if the aquarium owns
+// them. Objects without a name are named after the aquarium. Kinds the
+// cluster doesn't serve have nothing to delete.
+func (r *AquariumReconciler) deleteOwned(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	objs ...client.Object,
+) error {
+	for _, obj := range objs {
+		gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+		if err != nil {
+			return err
+		}
+
+		key := client.ObjectKey{Namespace: aquarium.Namespace, Name: obj.GetName()}
+		if key.Name == "" {
+			key.Name = aquarium.Name
+		}
+
+		if err := r.Get(ctx, key, obj); err != nil {
+			if apimeta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		if !metav1.IsControlledBy(obj, aquarium) {
+			continue
+		}
+
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete %s %s: %w", gvk.Kind, obj.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// exposureURL is the address visitors reach the aquarium at: the hostname of
+// its route, or else the address of its load balancer.
+func exposureURL(exposure *funv1alpha1.ExposureSpec, service *corev1.Service) string {
+	path := exposurePath(exposure)
+
+	if exposure.Route != "" && exposure.Hostname != "" {
+		return fmt.Sprintf("http://%s%s", exposure.Hostname, path)
+	}
+
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		switch {
+		case ingress.Hostname != "":
+			return fmt.Sprintf("http://%s%s", ingress.Hostname, path)
+		case ingress.IP != "":
+			return fmt.Sprintf("http://%s%s", ingress.IP, path)
+		}
+	}
+
+	return ""
+}
+
+func exposurePath(exposure *funv1alpha1.ExposureSpec) string {
+	if exposure.Path == "" {
+		return "/"
+	}
+
+	return exposure.Path
+}
+
+func newService(aquarium *funv1alpha1.Aquarium) *corev1.Service {
+	serviceType := aquarium.Spec.Exposure.ServiceType
+	if serviceType == "" {
+		serviceType = corev1.ServiceTypeClusterIP
+	}
+
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            aquarium.Name,
+			Namespace:       aquarium.Namespace,
+			Labels:          aquariumLabels(aquarium),
+			OwnerReferences: []metav1.OwnerReference{ownerReference(aquarium)},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     serviceType,
+			Selector: selectorLabels(aquarium),
+			Ports: []corev1.ServicePort{{
+				Name:       TankPortName,
+				Protocol:   corev1.ProtocolTCP,
+				Port:       servicePort,
+				TargetPort: intstr.FromString(TankPortName),
+			}},
+		},
+	}
+}
+
+func newIngress(aquarium *funv1alpha1.Aquarium) *networkingv1.Ingress {
+	exposure := aquarium.Spec.Exposure
+	pathType := networkingv1.PathTypePrefix
+
+	return &networkingv1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: networkingv1.SchemeGroupVersion.String(),
+			Kind:       "Ingress",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            aquarium.Name,
+			Namespace:       aquarium.Namespace,
+			Labels:          aquariumLabels(aquarium),
+			OwnerReferences: []metav1.OwnerReference{ownerReference(aquarium)},
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: exposure.IngressClassName,
+			Rules: []networkingv1.IngressRule{{
+				Host: exposure.Hostname,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     exposurePath(exposure),
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: aquarium.Name,
+									Port: networkingv1.ServiceBackendPort{Number: servicePort},
+								},
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+}
+
+// emptyHTTPRoute returns an HTTPRoute without any content, to read into.
+func emptyHTTPRoute() *unstructured.Unstructured {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(httpRouteGVK)
+
+	return route
+}
+
+func newHTTPRoute(aquarium *funv1alpha1.Aquarium) *unstructured.Unstructured {
+	exposure := aquarium.Spec.Exposure
+
+	parent := map[string]interface{}{}
+	if gateway := exposure.Gateway; gateway != nil {
+		parent["name"] = gateway.Name
+		if gateway.Namespace != "" {
+			parent["namespace"] = gateway.Namespace
+		}
+		if gateway.SectionName != "" {
+			parent["sectionName"] = gateway.SectionName
+		}
+	}
+
+	spec := map[string]interface{}{
+		"parentRefs": []interface{}{parent},
+		"rules": []interface{}{map[string]interface{}{
+			"matches": []interface{}{map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":  "PathPrefix",
+					"value": exposurePath(exposure),
+				},
+			}},
+			"backendRefs": []interface{}{map[string]interface{}{
+				"name": aquarium.Name,
+				"port": int64(servicePort),
+			}},
+		}},
+	}
+	if exposure.Hostname != "" {
+		spec["hostnames"] = []interface{}{exposure.Hostname}
+	}
+
+	route := emptyHTTPRoute()
+	route.SetName(aquarium.Name)
+	route.SetNamespace(aquarium.Namespace)
+	route.SetLabels(aquariumLabels(aquarium))
+	route.SetOwnerReferences([]metav1.OwnerReference{ownerReference(aquarium)})
+	route.Object["spec"] = spec
+
+	return route
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// setMissedFeedingCondition surfaces the missed feedings of an aquarium's feeding schedules.
+func (r *AquariumReconciler) setMissedFeedingCondition(ctx context.Context, aquarium *funv1alpha1.Aquarium) error {
+	var schedules funv1alpha1.FeedingScheduleList
+	if err := r.List(
+		ctx,
+		&schedules,
+		client.InNamespace(aquarium.Namespace),
+		client.MatchingFields{feedingAquariumIndexKey: aquarium.Name},
+	); err != nil {
+		return fmt.Errorf("failed to list feeding schedules: %w", err)
+	}
+
+	var missed []string
+	for i := range schedules.Items {
+		condition := apimeta.FindStatusCondition(schedules.Items[i].Status.Conditions, MissedFeeding)
+		if condition != nil && condition.Status == metav1.ConditionTrue {
+			missed = append(missed, fmt.Sprintf("%s: %s", schedules.Items[i].Name, condition.Message))
+		}
+	}
+
+	if len(missed) > 0 {
+		setCondition(aquarium, MissedFeeding, metav1.ConditionTrue, FeedingMissed, strings.Join(missed, "; "))
+		return nil
+	}
+
+	setCondition(aquarium, MissedFeeding, metav1.ConditionFalse, FeedingsOnSchedule, "No feedings were missed")
+	return nil
+}
+
+// feedingScheduleAquarium returns the aquarium a feeding schedule feeds.
+func feedingScheduleAquarium(obj client.Object) string {
+	if schedule, ok := obj.(*funv1alpha1.FeedingSchedule); ok {
+		return schedule.Spec.Aquarium
+	}
+
+	return ""
+}
@@ -0,0 +1,417 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// maxMissedFeedings caps how many missed feedings are counted per feeding.
+const maxMissedFeedings = 100
+
+// maxCronJobNameLength is the longest name a CronJob may have, leaving room
+// for the suffix of the Jobs it creates.
+const maxCronJobNameLength = 52
+
+// FeedingScheduleReconciler reconciles a FeedingSchedule object
+type FeedingScheduleReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=feedingschedules,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=feedingschedules/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=feedingschedules/finalizers,verbs=update
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile turns every feeding of a schedule into a CronJob and reports when
+// the tanks were last fed, are fed next and which feedings were missed.
+func (r *FeedingScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithValues("feedingschedule", req.Name, "ns", req.Namespace)
+
+	var schedule funv1alpha1.FeedingSchedule
+	if err := r.Get(ctx, req.NamespacedName, &schedule); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var aquarium funv1alpha1.Aquarium
+	err := r.Get(ctx, client.ObjectKey{Namespace: schedule.Namespace, Name: schedule.Spec.Aquarium}, &aquarium)
+	if client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get aquarium %s: %w", schedule.Spec.Aquarium, err)
+	}
+	if err != nil {
+		log.Info("aquarium not found", "aquarium", schedule.Spec.Aquarium)
+		setScheduleCondition(&schedule, Ready, metav1.ConditionFalse, AquariumNotFound,
+			fmt.Sprintf("Aquarium %s does not exist", schedule.Spec.Aquarium))
+		return ctrl.Result{}, r.updateScheduleStatus(ctx, &schedule)
+	}
+
+	// The schedule goes away with the aquarium it feeds.
+	if !isOwnedBy(&schedule, &aquarium) {
+		if err := controllerutil.SetOwnerReference(&aquarium, &schedule, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.Update(ctx, &schedule); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to set the owner of the feeding schedule: %w", err)
+		}
+	}
+
+	crons := make(map[string]cron.Schedule, len(schedule.Spec.Feedings))
+	for _, feeding := range schedule.Spec.Feedings {
+		parsed, err := ParseCron(feeding.Schedule, schedule.Spec.TimeZone)
+		if err != nil {
+			setScheduleCondition(&schedule, Ready, metav1.ConditionFalse, InvalidSchedule,
+				fmt.Sprintf("Feeding %s has an invalid schedule: %v", feeding.Name, err))
+			return ctrl.Result{}, r.updateScheduleStatus(ctx, &schedule)
+		}
+		crons[feeding.Name] = parsed
+	}
+
+	// The tanks fed follow the schedule of the aquarium, so the cron jobs are
+	// applied again whenever it moves on.
+	now := time.Now()
+	tanks := evaluateSchedule(&aquarium, now)
+
+	cronJobs := make(map[string]*batchv1.CronJob, len(schedule.Spec.Feedings))
+	for _, feeding := range schedule.Spec.Feedings {
+		cronJob := newCronJob(&schedule, &aquarium, feeding, tanks.Tanks)
+		if err := r.Patch(
+			ctx,
+			cronJob,
+			client.Apply,
+			client.ForceOwnership,
+			client.FieldOwner(AquariumOperator),
+		); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to apply cron job %s: %w", cronJob.Name, err)
+		}
+		cronJobs[feeding.Name] = cronJob
+	}
+
+	if err := r.deleteStaleCronJobs(ctx, &schedule, cronJobs); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	switch {
+	case schedule.Spec.Suspend:
+		schedule.Status.ActiveSince = nil
+	case schedule.Status.ActiveSince == nil:
+		schedule.Status.ActiveSince = &metav1.Time{Time: now}
+	}
+
+	recheck := time.Duration(0)
+	var missed []string
+	schedule.Status.Feedings = make([]funv1alpha1.FeedingStatus, 0, len(schedule.Spec.Feedings))
+	for _, feeding := range schedule.Spec.Feedings {
+		status, overdue := feedingStatus(&schedule, feeding, crons[feeding.Name], cronJobs[feeding.Name], now)
+		if schedule.Spec.Suspend {
+			status.MissedFeedings = 0
+		}
+		schedule.Status.Feedings = append(schedule.Status.Feedings, status)
+
+		if status.MissedFeedings > 0 {
+			missed = append(missed, fmt.Sprintf("%s missed %d feedings", feeding.Name, status.MissedFeedings))
+		}
+		if wait := overdue.Sub(now); recheck == 0 || wait < recheck {
+			recheck = wait
+		}
+	}
+
+	setScheduleCondition(&schedule, Ready, metav1.ConditionTrue, FeedingsScheduled,
+		fmt.Sprintf("%d feedings are scheduled", len(schedule.Spec.Feedings)))
+	if len(missed) > 0 {
+		setScheduleCondition(&schedule, MissedFeeding, metav1.ConditionTrue, FeedingMissed,
+			strings.Join(missed, "; "))
+	} else {
+		setScheduleCondition(&schedule, MissedFeeding, metav1.ConditionFalse, FeedingsOnSchedule,
+			"No feedings were missed")
+	}
+
+	if err := r.updateScheduleStatus(ctx, &schedule); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if schedule.Spec.Suspend {
+		return ctrl.Result{}, nil
+	}
+
+	// Check back once the next feeding is overdue, in case it is missed, or
+	// when the tanks fed change, whichever comes first.
+	result := ctrl.Result{RequeueAfter: recheck + time.Second}
+	if !tanks.Next.IsZero() {
+		result = requeueBefore(result, time.Until(tanks.Next))
+	}
+
+	return result, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *FeedingScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&funv1alpha1.FeedingSchedule{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&batchv1.CronJob{}).
+		Watches(
+			&funv1alpha1.Aquarium{},
+			handler.EnqueueRequestsFromMapFunc(r.schedulesOfAquarium),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Complete(r)
+}
+
+// schedulesOfAquarium maps an Aquarium to the feeding schedules feeding it.
+func (r *FeedingScheduleReconciler) schedulesOfAquarium(
+	ctx context.Context,
+	obj client.Object,
+) []reconcile.Request {
+	var schedules funv1alpha1.FeedingScheduleList
+	if err := r.List(
+		ctx,
+		&schedules,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{feedingAquariumIndexKey: obj.GetName()},
+	); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list feeding schedules of aquarium", "aquarium", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(schedules.Items))
+	for i := range schedules.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&schedules.Items[i])})
+	}
+
+	return requests
+}
+
+// deleteStaleCronJobs deletes the CronJobs of feedings that were removed from the schedule.
+func (r *FeedingScheduleReconciler) deleteStaleCronJobs(
+	ctx context.Context,
+	schedule *funv1alpha1.FeedingSchedule,
+	current map[string]*batchv1.CronJob,
+) error {
+	var cronJobs batchv1.CronJobList
+	if err := r.List(
+		ctx,
+		&cronJobs,
+		client.InNamespace(schedule.Namespace),
+		client.MatchingLabels{FeedingScheduleKey: schedule.Name},
+	); err != nil {
+		return fmt.Errorf("failed to list cron jobs: %w", err)
+	}
+
+	keep := make(map[string]bool, len(current))
+	for _, cronJob := range current {
+		keep[cronJob.Name] = true
+	}
+
+	for i := range cronJobs.Items {
+		cronJob := &cronJobs.Items[i]
+		if keep[cronJob.Name] || !metav1.IsControlledBy(cronJob, schedule) {
+			continue
+		}
+
+		log.FromContext(ctx).Info("deleting cron job of removed feeding", "cronjob", cronJob.Name)
+		err := r.Delete(ctx, cronJob, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete cron job %s: %w", cronJob.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *FeedingScheduleReconciler) updateScheduleStatus(
+	ctx context.Context,
+	schedule *funv1alpha1.FeedingSchedule,
+) error {
+	schedule.Status.ObservedGeneration = schedule.Generation
+	if err := r.Status().Update(ctx, schedule); err != nil {
+		return fmt.Errorf("failed to update feeding schedule status: %w", err)
+	}
+
+	return nil
+}
+
+// feedingStatus reports on a feeding and returns when its next feeding is
+// overdue. Feedings that should have started more than the grace period ago
+// without the CronJob scheduling them are missed.
+func feedingStatus(
+	schedule *funv1alpha1.FeedingSchedule,
+	feeding funv1alpha1.Feeding,
+	parsed cron.Schedule,
+	cronJob *batchv1.CronJob,
+	now time.Time,
+) (funv1alpha1.FeedingStatus, time.Time) {
+	status := funv1alpha1.FeedingStatus{
+		Name:                      feeding.Name,
+		LastFeedingTime:           cronJob.Status.LastScheduleTime,
+		LastSuccessfulFeedingTime: cronJob.Status.LastSuccessfulTime,
+		NextFeedingTime:           &metav1.Time{Time: parsed.Next(now)},
+	}
+
+	// Feedings are due from the last one served, or from when the feeding
+	// was created, but never from before the schedule was last resumed.
+	since := cronJob.CreationTimestamp.Time
+	if last := cronJob.Status.LastScheduleTime; last != nil {
+		since = last.Time
+	}
+	if active := schedule.Status.ActiveSince; active != nil && active.After(since) {
+		since = active.Time
+	}
+
+	grace := feedingGracePeriod(schedule)
+	due := parsed.Next(since)
+	for due.Add(grace).Before(now) && status.MissedFeedings < maxMissedFeedings {
+		status.MissedFeedings++
+		due = parsed.Next(due)
+	}
+
+	return status, due.Add(grace)
+}
+
+func feedingGracePeriod(schedule *funv1alpha1.FeedingSchedule) time.Duration {
+	if grace := schedule.Spec.GracePeriod; grace != nil {
+		return grace.Duration
+	}
+
+	return funv1alpha1.DefaultFeedingGracePeriod.Duration
+}
+
+func setScheduleCondition(
+	schedule *funv1alpha1.FeedingSchedule,
+	conditionType string,
+	status metav1.ConditionStatus,
+	reason, message string,
+) {
+	apimeta.SetStatusCondition(&schedule.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: schedule.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// cronJobName names the CronJob serving a feeding. Names too long for a
+// CronJob are cut short and kept apart by a hash of the full name.
+func cronJobName(schedule *funv1alpha1.FeedingSchedule, feeding funv1alpha1.Feeding) string {
+	name := schedule.Name + "-" + feeding.Name
+	if len(name) <= maxCronJobNameLength {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:4])
+	prefix := strings.TrimRight(name[:maxCronJobNameLength-len(hash)-1], "-.")
+
+	return prefix + "-" + hash
+}
+
+// newCronJob builds the CronJob serving a feeding to the given number of
+// tanks. It is labeled like the other objects of the aquarium but owned by
+// the feeding schedule.
+func newCronJob(
+	schedule *funv1alpha1.FeedingSchedule,
+	aquarium *funv1alpha1.Aquarium,
+	feeding funv1alpha1.Feeding,
+	tanks int32,
+) *batchv1.CronJob {
+	labels := aquariumLabels(aquarium)
+	labels[FeedingScheduleKey] = schedule.Name
+
+	// The feeder pods must not be mistaken for tanks, so they don't carry the tank selector.
+	podLabels := map[string]string{
+		AppNameKey:         FeederAppName,
+		AppInstanceKey:     schedule.Name,
+		AppManagedByKey:    AquariumOperator,
+		FeedingScheduleKey: schedule.Name,
+	}
+
+	grace := int64(feedingGracePeriod(schedule).Seconds())
+
+	return &batchv1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+			Kind:       "CronJob",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cronJobName(schedule, feeding),
+			Namespace: schedule.Namespace,
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion:         funv1alpha1.GroupVersion.String(),
+				Kind:               "FeedingSchedule",
+				Name:               schedule.Name,
+				UID:                schedule.UID,
+				Controller:         pointer.Bool(true),
+				BlockOwnerDeletion: pointer.Bool(true),
+			}},
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                feeding.Schedule,
+			TimeZone:                schedule.Spec.TimeZone,
+			Suspend:                 pointer.Bool(schedule.Spec.Suspend),
+			ConcurrencyPolicy:       batchv1.ForbidConcurrent,
+			StartingDeadlineSeconds: pointer.Int64(grace),
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: pointer.Int32(2),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+						Spec: corev1.PodSpec{
+							RestartPolicy: corev1.RestartPolicyOnFailure,
+							Containers: []corev1.Container{{
+								Name:  FeederContainerName,
+								Image: funv1alpha1.DefaultFeederImage,
+								Command: []string{"sh", "-c",
+									`echo "Feeding ${PORTION_PER_TANK}g of $FOOD to each of the $TANKS tanks of $AQUARIUM"`},
+								Env: []corev1.EnvVar{
+									{Name: "AQUARIUM", Value: aquarium.Name},
+									{Name: "FOOD", Value: feeding.Food},
+									{Name: "PORTION_PER_TANK", Value: feeding.PortionPerTank.String()},
+									{Name: "TANKS", Value: fmt.Sprint(tanks)},
+								},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
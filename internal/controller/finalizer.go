@@ -0,0 +1,241 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// DefaultDrainRequeue is how often a draining aquarium is checked on, unless
+// configured otherwise.
+const DefaultDrainRequeue = 5 * time.Second
+
+// ReservationReleaser releases whatever an aquarium reserved outside of the
+// cluster once the aquarium has been torn down.
+type ReservationReleaser interface {
+	Release(ctx context.Context, aquarium *funv1alpha1.Aquarium) error
+}
+
+// finalize tears an aquarium down in order: the tanks are scaled to zero, their
+// pods are waited on, a Closed event is emitted and external reservations are
+// released before the finalizer is removed. The drain is skipped once the
+// teardown timeout has passed or the force delete annotation is set.
+func (r *AquariumReconciler) finalize(ctx context.Context, aquarium *funv1alpha1.Aquarium) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(aquarium, AquariumFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	log := log.FromContext(ctx)
+	force := aquarium.Annotations[ForceDeleteAnnotation] == "true"
+	timeout := teardownTimeout(aquarium)
+	deadline := aquarium.DeletionTimestamp.Add(timeout)
+
+	switch {
+	case force:
+		log.Info("skipping the drain of a force deleted aquarium")
+		r.events.Eventf(aquarium, corev1.EventTypeWarning, TeardownForced,
+			"The tanks were not drained because the aquarium was force deleted")
+	case time.Now().After(deadline):
+		log.Info("gave up draining the aquarium", "timeout", timeout)
+		r.events.Eventf(aquarium, corev1.EventTypeWarning, TeardownTimedOut,
+			"The tanks did not drain within %s", timeout)
+	default:
+		drained, err := r.drain(ctx, aquarium)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !drained {
+			requeue := orDefault(r.DrainRequeue, DefaultDrainRequeue)
+			return ctrl.Result{RequeueAfter: minDuration(requeue, time.Until(deadline))}, nil
+		}
+	}
+
+	r.events.Eventf(aquarium, corev1.EventTypeNormal, Closed, "The aquarium is closed")
+
+	if r.Reservations != nil {
+		if err := r.Reservations.Release(ctx, aquarium); err != nil && !force {
+			return ctrl.Result{}, fmt.Errorf("failed to release reservations: %w", err)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(aquarium, AquariumFinalizer)
+	if err := r.Update(ctx, aquarium); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to remove finalizer: %w", err)
+	}
+	r.events.Forget(aquarium.UID)
+
+	return ctrl.Result{}, nil
+}
+
+// drain scales the tanks of an aquarium to zero and reports whether all of
+// their pods are gone, including the pods of legacy ReplicaSets.
+func (r *AquariumReconciler) drain(ctx context.Context, aquarium *funv1alpha1.Aquarium) (bool, error) {
+	// The autoscaler would scale the tanks right back up.
+	if err := r.deleteAutoscaler(ctx, aquarium); err != nil {
+		return false, err
+	}
+
+	// Canary and StatefulSet tanks are drained with the others.
+	workloads := []client.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: aquarium.Name}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: canaryName(aquarium)}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: aquarium.Name}},
+	}
+	for _, workload := range workloads {
+		if err := r.scaleToZero(ctx, aquarium, workload); err != nil {
+			return false, err
+		}
+	}
+
+	var pods corev1.PodList
+	if err := r.List(
+		ctx,
+		&pods,
+		client.InNamespace(aquarium.Namespace),
+		client.MatchingLabels(selectorLabels(aquarium)),
+	); err != nil {
+		return false, fmt.Errorf("failed to list tanks: %w", err)
+	}
+
+	legacy, err := r.drainLegacyReplicaSets(ctx, aquarium, workloads[0])
+	if err != nil {
+		return false, err
+	}
+
+	return len(pods.Items) == 0 && legacy == 0, nil
+}
+
+// drainLegacyReplicaSets scales the legacy ReplicaSets of an aquarium to zero
+// and counts their pods that are left. Those are the ReplicaSets adopted by
+// migrateDeployment, and those of a legacy Deployment of the aquarium that
+// was never migrated, whose pods lack the selector labels of the aquarium.
+func (r *AquariumReconciler) drainLegacyReplicaSets(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	deploy client.Object,
+) (int, error) {
+	replicaSets, err := r.legacyReplicaSets(ctx, aquarium)
+	if err != nil {
+		return 0, err
+	}
+
+	legacy := map[types.UID]bool{}
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		owner := metav1.GetControllerOf(rs)
+		switch {
+		case owner == nil && isOwnedBy(rs, aquarium):
+			if err := r.scaleToZero(ctx, aquarium, rs); err != nil {
+				return 0, err
+			}
+		case owner != nil && metav1.IsControlledBy(deploy, aquarium) && owner.UID == deploy.GetUID():
+			// Drained with the Deployment.
+		default:
+			continue
+		}
+		legacy[rs.UID] = true
+	}
+	if len(legacy) == 0 {
+		return 0, nil
+	}
+
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+
+	var pods corev1.PodList
+	if err := reader.List(
+		ctx,
+		&pods,
+		client.InNamespace(aquarium.Namespace),
+		client.MatchingLabels{AppKey: AquariumValue},
+	); err != nil {
+		return 0, fmt.Errorf("failed to list legacy tanks: %w", err)
+	}
+
+	left := 0
+	for i := range pods.Items {
+		if owner := metav1.GetControllerOf(&pods.Items[i]); owner != nil && legacy[owner.UID] {
+			left++
+		}
+	}
+
+	return left, nil
+}
+
+// scaleToZero scales a workload running tanks of an aquarium to zero, unless it
+// is gone, already going or was never the aquarium's. The aquarium must control
+// the workload, except for the legacy ReplicaSets it adopted.
+func (r *AquariumReconciler) scaleToZero(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	workload client.Object,
+) error {
+	key := types.NamespacedName{Namespace: aquarium.Namespace, Name: workload.GetName()}
+	if err := r.Get(ctx, key, workload); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	var replicas *int32
+	switch workload := workload.(type) {
+	case *appsv1.Deployment:
+		replicas = workload.Spec.Replicas
+	case *appsv1.StatefulSet:
+		replicas = workload.Spec.Replicas
+	case *appsv1.ReplicaSet:
+		replicas = workload.Spec.Replicas
+	}
+
+	// Adopted legacy ReplicaSets are owned by the aquarium without it being their controller.
+	owned := metav1.IsControlledBy(workload, aquarium)
+	if _, ok := workload.(*appsv1.ReplicaSet); ok {
+		owned = isOwnedBy(workload, aquarium)
+	}
+	if !owned {
+		return nil
+	}
+	if !workload.GetDeletionTimestamp().IsZero() || (replicas != nil && *replicas == 0) {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("scaling tanks to zero", "workload", key.Name)
+	patch := []byte(`{"spec":{"replicas":0}}`)
+	if err := r.Patch(
+		ctx,
+		workload,
+		client.RawPatch(types.MergePatchType, patch),
+		client.FieldOwner(AquariumOperator),
+	); err != nil {
+		return fmt.Errorf("failed to scale tanks to zero: %w", err)
+	}
+
+	return nil
+}
+
+func teardownTimeout(aquarium *funv1alpha1.Aquarium) time.Duration {
+	if timeout := aquarium.Spec.Teardown.Timeout; timeout != nil {
+		return timeout.Duration
+	}
+
+	return funv1alpha1.DefaultTeardownTimeout.Duration
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if b > 0 && b < a {
+		return b
+	}
+
+	return a
+}
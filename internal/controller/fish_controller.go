@@ -0,0 +1,123 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// FishReconciler reconciles a Fish object
+type FishReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=fish,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=fish/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=fun.tydanny.com,resources=fish/finalizers,verbs=update
+
+// Reconcile places fish in their aquarium, refusing placements in aquaria or
+// tanks that don't exist.
+func (r *FishReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithValues("fish", req.Name, "ns", req.Namespace)
+
+	var fish funv1alpha1.Fish
+	if err := r.Get(ctx, req.NamespacedName, &fish); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var aquarium *funv1alpha1.Aquarium
+	var found funv1alpha1.Aquarium
+	err := r.Get(ctx, client.ObjectKey{Namespace: fish.Namespace, Name: fish.Spec.Aquarium}, &found)
+	if client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get aquarium %s: %w", fish.Spec.Aquarium, err)
+	}
+	if err == nil {
+		aquarium = &found
+	}
+
+	placement := placeFish(&fish, aquarium)
+	if !placement.Placed {
+		log.Info("refusing placement", "aquarium", fish.Spec.Aquarium, "reason", placement.Reason)
+	}
+
+	status := metav1.ConditionFalse
+	if placement.Placed {
+		status = metav1.ConditionTrue
+	}
+	apimeta.SetStatusCondition(&fish.Status.Conditions, metav1.Condition{
+		Type:               Placed,
+		Status:             status,
+		ObservedGeneration: fish.Generation,
+		Reason:             placement.Reason,
+		Message:            placement.Message,
+	})
+	fish.Status.ObservedGeneration = fish.Generation
+
+	if err := r.Status().Update(ctx, &fish); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update fish status: %w", err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *FishReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&funv1alpha1.Fish{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&funv1alpha1.Aquarium{},
+			handler.EnqueueRequestsFromMapFunc(r.fishInAquarium),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Complete(r)
+}
+
+// fishInAquarium maps an Aquarium to the fish that reference it.
+func (r *FishReconciler) fishInAquarium(ctx context.Context, obj client.Object) []reconcile.Request {
+	var fish funv1alpha1.FishList
+	if err := r.List(
+		ctx,
+		&fish,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{fishAquariumIndexKey: obj.GetName()},
+	); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list fish in aquarium", "aquarium", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(fish.Items))
+	for i := range fish.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&fish.Items[i])})
+	}
+
+	return requests
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// healthReport is the outcome of evaluating the tanks of an aquarium.
+type healthReport struct {
+	Health  funv1alpha1.FishHealth
+	Status  metav1.ConditionStatus
+	Reason  string
+	Message string
+}
+
+// evaluateHealth derives the fish health of an aquarium from its Deployment.
+//   - Healthy when every tank is ready.
+//   - Kinda when at least the degraded threshold of tanks is ready, or when a
+//     rollout is progressing and some tanks are ready.
+//   - Unhealthy below that.
+//   - Unknown when the Deployment is missing.
+func evaluateHealth(aquarium *funv1alpha1.Aquarium, deploy *appsv1.Deployment) healthReport {
+	if deploy == nil {
+		return healthReport{
+			Health:  funv1alpha1.Unknown,
+			Status:  metav1.ConditionUnknown,
+			Reason:  AquariumHealthUnknown,
+			Message: "The aquarium has no tanks yet",
+		}
+	}
+
+	desired := desiredTanks(aquarium, deploy)
+	ready := deploy.Status.ReadyReplicas
+
+	if ready >= desired {
+		return healthReport{
+			Health:  funv1alpha1.Healthy,
+			Status:  metav1.ConditionTrue,
+			Reason:  AquariumIsHealthy,
+			Message: "The aquarium is ready!",
+		}
+	}
+
+	threshold := degradedThreshold(aquarium, desired)
+	if ready > 0 && ready >= threshold {
+		return healthReport{
+			Health:  funv1alpha1.KindOfHealthy,
+			Status:  metav1.ConditionFalse,
+			Reason:  AquariumIsKindaHealthy,
+			Message: fmt.Sprintf("%d of %d tanks are ready", ready, desired),
+		}
+	}
+
+	if ready > 0 && rolloutProgressing(deploy) {
+		return healthReport{
+			Health:  funv1alpha1.KindOfHealthy,
+			Status:  metav1.ConditionFalse,
+			Reason:  AquariumIsRollingOut,
+			Message: fmt.Sprintf("%d of %d tanks are ready while the tanks roll out", ready, desired),
+		}
+	}
+
+	return healthReport{
+		Health:  funv1alpha1.Unhealthy,
+		Status:  metav1.ConditionFalse,
+		Reason:  AquariumIsUnHealthy,
+		Message: fmt.Sprintf("The aquarium is not ready :( only %d of %d tanks are ready", ready, desired),
+	}
+}
+
+// degradedThreshold resolves spec.health.degradedThreshold to a number of tanks,
+// rounding percentages up so a threshold is never met by fewer tanks than asked for.
+func degradedThreshold(aquarium *funv1alpha1.Aquarium, desired int32) int32 {
+	threshold := aquarium.Spec.Health.DegradedThreshold
+	if threshold == nil {
+		threshold = &funv1alpha1.DefaultDegradedThreshold
+	}
+
+	value, err := intstr.GetScaledValueFromIntOrPercent(threshold, int(desired), true)
+	if err != nil {
+		value, _ = intstr.GetScaledValueFromIntOrPercent(
+			&funv1alpha1.DefaultDegradedThreshold, int(desired), true,
+		)
+	}
+
+	return int32(value)
+}
+
+// rolloutProgressing reports whether the Deployment is still rolling out its latest template.
+func rolloutProgressing(deploy *appsv1.Deployment) bool {
+	if deploy.Generation > deploy.Status.ObservedGeneration {
+		return true
+	}
+
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+
+	return deploy.Status.UpdatedReplicas < replicas
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// Field indexes shared by the controllers.
+const (
+	// locationIndexKey indexes aquaria by the name of the Location they are mapped by.
+	locationIndexKey = ".spec.location"
+	// fishAquariumIndexKey indexes fish by their spec.aquarium.
+	fishAquariumIndexKey = ".spec.aquarium"
+	// fishSpeciesIndexKey indexes fish by their spec.species.
+	fishSpeciesIndexKey = ".spec.species"
+	// feedingAquariumIndexKey indexes feeding schedules by their spec.aquarium.
+	feedingAquariumIndexKey = ".spec.aquarium"
+	// waterReadingAquariumIndexKey indexes water readings by their spec.aquarium.
+	waterReadingAquariumIndexKey = ".spec.aquarium"
+)
+
+// SetupFieldIndexes registers the field indexes the controllers list objects by.
+// It must be called once, before the controllers are set up.
+func SetupFieldIndexes(ctx context.Context, mgr ctrl.Manager) error {
+	indexer := mgr.GetFieldIndexer()
+
+	if err := indexer.IndexField(ctx, &funv1alpha1.Aquarium{}, locationIndexKey, func(obj client.Object) []string {
+		return []string{locationName(obj.(*funv1alpha1.Aquarium).Spec.Location)}
+	}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(ctx, &funv1alpha1.Fish{}, fishAquariumIndexKey, func(obj client.Object) []string {
+		return []string{obj.(*funv1alpha1.Fish).Spec.Aquarium}
+	}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(ctx, &funv1alpha1.Fish{}, fishSpeciesIndexKey, func(obj client.Object) []string {
+		return []string{obj.(*funv1alpha1.Fish).Spec.Species}
+	}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(
+		ctx,
+		&funv1alpha1.FeedingSchedule{},
+		feedingAquariumIndexKey,
+		func(obj client.Object) []string {
+			return []string{obj.(*funv1alpha1.FeedingSchedule).Spec.Aquarium}
+		},
+	); err != nil {
+		return err
+	}
+
+	return indexer.IndexField(
+		ctx,
+		&funv1alpha1.WaterReading{},
+		waterReadingAquariumIndexKey,
+		func(obj client.Object) []string {
+			return []string{obj.(*funv1alpha1.WaterReading).Spec.Aquarium}
+		},
+	)
+}
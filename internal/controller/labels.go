@@ -0,0 +1,34 @@
+package controller
+
+import (
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// selectorLabels returns the labels that uniquely select the tanks of an aquarium.
+// They are immutable once a Deployment has been created with them.
+func selectorLabels(aquarium *funv1alpha1.Aquarium) map[string]string {
+	return map[string]string{
+		AppNameKey:     AquariumAppName,
+		AppInstanceKey: aquarium.Name,
+		AquariumUIDKey: string(aquarium.UID),
+	}
+}
+
+// trackSelectorLabels returns the labels that select the tanks of an aquarium
+// on a rollout track, so the stable and canary Deployments never select each
+// other's tanks.
+func trackSelectorLabels(aquarium *funv1alpha1.Aquarium, track string) map[string]string {
+	labels := selectorLabels(aquarium)
+	labels[TrackKey] = track
+
+	return labels
+}
+
+// aquariumLabels returns the labels put on every object owned by an aquarium.
+func aquariumLabels(aquarium *funv1alpha1.Aquarium) map[string]string {
+	labels := selectorLabels(aquarium)
+	labels[AppManagedByKey] = AquariumOperator
+	labels[LocatedAt] = aquarium.Spec.Location
+
+	return labels
+}
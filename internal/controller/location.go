@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// locationName is the name of the Location an aquarium at the location is
+// mapped by: the location lowercased, with the characters a name can't hold
+// replaced by dashes, so an aquarium in "Monterey Bay" maps to monterey-bay.
+func locationName(location string) string {
+	return strings.Trim(nonNameCharacters.ReplaceAllString(strings.ToLower(location), "-"), "-")
+}
+
+// getLocation returns the Location an aquarium is at. It is nil when the
+// location isn't mapped, in which case the tanks may run on any node.
+func (r *AquariumReconciler) getLocation(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+) (*funv1alpha1.Location, error) {
+	name := locationName(aquarium.Spec.Location)
+	if name == "" {
+		return nil, nil
+	}
+
+	var location funv1alpha1.Location
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, &location); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get location %s: %w", name, err)
+	}
+
+	return &location, nil
+}
+
+// setSchedulableCondition reports whether any node can run the tanks of an aquarium.
+func (r *AquariumReconciler) setSchedulableCondition(ctx context.Context, aquarium *funv1alpha1.Aquarium) error {
+	location, err := r.getLocation(ctx, aquarium)
+	if err != nil {
+		return err
+	}
+
+	if location == nil {
+		setCondition(aquarium, Schedulable, metav1.ConditionTrue, LocationNotMapped,
+			fmt.Sprintf("Location %s is not mapped to nodes, tanks may run on any node", aquarium.Spec.Location))
+		return nil
+	}
+
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	matching := 0
+	for i := range nodes.Items {
+		if nodeMatchesLocation(&nodes.Items[i], location) {
+			matching++
+		}
+	}
+
+	if matching == 0 {
+		log.FromContext(ctx).Info("no nodes match the location", "location", location.Name)
+		setCondition(aquarium, Schedulable, metav1.ConditionFalse, NoNodesMatchLocation,
+			fmt.Sprintf("No nodes match location %s", location.Name))
+		return nil
+	}
+
+	setCondition(aquarium, Schedulable, metav1.ConditionTrue, NodesMatchLocation,
+		fmt.Sprintf("%d nodes match location %s", matching, location.Name))
+	return nil
+}
+
+// applyLocation sets the scheduling constraints of a location on the pod spec of the tanks.
+func applyLocation(podSpec *corev1.PodSpec, location *funv1alpha1.Location) {
+	if location == nil {
+		return
+	}
+
+	podSpec.NodeSelector = location.Spec.NodeSelector
+	podSpec.Affinity = location.Spec.Affinity
+	podSpec.Tolerations = location.Spec.Tolerations
+}
+
+// nodeMatchesLocation reports whether a tank at the location could be scheduled
+// onto the node, judging by its labels, required node affinity and taints.
+func nodeMatchesLocation(node *corev1.Node, location *funv1alpha1.Location) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+
+	if !labels.SelectorFromSet(location.Spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+		return false
+	}
+
+	if affinity := location.Spec.Affinity; affinity != nil && affinity.NodeAffinity != nil {
+		if required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil &&
+			!nodeMatchesSelectorTerms(node, required.NodeSelectorTerms) {
+			return false
+		}
+	}
+
+	return toleratesNode(node, location.Spec.Tolerations)
+}
+
+// nodeMatchesSelectorTerms reports whether the node matches any of the terms.
+func nodeMatchesSelectorTerms(node *corev1.Node, terms []corev1.NodeSelectorTerm) bool {
+	for _, term := range terms {
+		if nodeMatchesSelectorTerm(node, term) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nodeMatchesSelectorTerm reports whether the node matches all requirements of
+// the term. A term without requirements matches no nodes.
+func nodeMatchesSelectorTerm(node *corev1.Node, term corev1.NodeSelectorTerm) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+
+	for _, expr := range term.MatchExpressions {
+		if !nodeSelectorRequirementMatches(expr, labels.Set(node.Labels)) {
+			return false
+		}
+	}
+
+	// metadata.name is the only field nodes can be selected by.
+	for _, expr := range term.MatchFields {
+		if expr.Key != "metadata.name" ||
+			!nodeSelectorRequirementMatches(expr, labels.Set{expr.Key: node.Name}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+var nodeSelectorOperators = map[corev1.NodeSelectorOperator]selection.Operator{
+	corev1.NodeSelectorOpIn:           selection.In,
+	corev1.NodeSelectorOpNotIn:        selection.NotIn,
+	corev1.NodeSelectorOpExists:       selection.Exists,
+	corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	corev1.NodeSelectorOpGt:           selection.GreaterThan,
+	corev1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+func nodeSelectorRequirementMatches(expr corev1.NodeSelectorRequirement, set labels.Set) bool {
+	op, ok := nodeSelectorOperators[expr.Operator]
+	if !ok {
+		return false
+	}
+
+	requirement, err := labels.NewRequirement(expr.Key, op, expr.Values)
+	if err != nil {
+		return false
+	}
+
+	return requirement.Matches(set)
+}
+
+// toleratesNode reports whether the tolerations tolerate every taint that keeps pods off the node.
+func toleratesNode(node *corev1.Node, tolerations []corev1.Toleration) bool {
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+
+		tolerated := false
+		for j := range tolerations {
+			if tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+
+	return true
+}
+
+// aquariaAtLocation maps a Location to the aquaria at it.
+func (r *AquariumReconciler) aquariaAtLocation(ctx context.Context, obj client.Object) []reconcile.Request {
+	var aquaria funv1alpha1.AquariumList
+	if err := r.List(ctx, &aquaria, client.MatchingFields{locationIndexKey: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list aquaria at location", "location", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(aquaria.Items))
+	for _, aquarium := range aquaria.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&aquarium)})
+	}
+
+	return requests
+}
+
+// aquariaAtMappedLocations maps a Node to every aquarium at a mapped location,
+// as the node may have started or stopped matching any of them.
+func (r *AquariumReconciler) aquariaAtMappedLocations(ctx context.Context, _ client.Object) []reconcile.Request {
+	var locations funv1alpha1.LocationList
+	if err := r.List(ctx, &locations); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list locations")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range locations.Items {
+		requests = append(requests, r.aquariaAtLocation(ctx, &locations.Items[i])...)
+	}
+
+	return requests
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// maxWindowChain caps how many back to back occurrences of a window are
+// merged when working out when it closes.
+const maxWindowChain = 100
+
+// maintenanceState is whether an aquarium is in maintenance and when that changes.
+type maintenanceState struct {
+	// Paused is set while the aquarium carries the paused annotation.
+	Paused bool
+	// Window is the name of the open maintenance window, empty outside of one.
+	Window string
+	// Until is when the open window closes.
+	Until time.Time
+	// Next is when the next window opens, zero without one.
+	Next time.Time
+	// Err reports a window that can't be parsed. No window opens while it is set.
+	Err error
+}
+
+// active reports whether the tanks are under maintenance.
+func (m maintenanceState) active() bool {
+	return m.Paused || m.Window != ""
+}
+
+// evaluateMaintenance works out whether an aquarium is in maintenance at now.
+func evaluateMaintenance(aquarium *funv1alpha1.Aquarium, now time.Time) maintenanceState {
+	state := maintenanceState{Paused: aquarium.Annotations[PausedAnnotation] == "true"}
+
+	for _, window := range aquarium.Spec.Maintenance.Windows {
+		schedule, err := ParseCron(window.Schedule, aquarium.Spec.Maintenance.TimeZone)
+		if err != nil {
+			return maintenanceState{
+				Paused: state.Paused,
+				Err:    fmt.Errorf("maintenance window %s has an invalid schedule: %w", window.Name, err),
+			}
+		}
+		if window.Duration.Duration <= 0 {
+			continue
+		}
+
+		if until, ok := windowOpenUntil(schedule, window.Duration.Duration, now); ok && until.After(state.Until) {
+			state.Window = window.Name
+			state.Until = until
+		}
+		if next := schedule.Next(now); !next.IsZero() && (state.Next.IsZero() || next.Before(state.Next)) {
+			state.Next = next
+		}
+	}
+
+	return state
+}
+
+// windowOpenUntil reports whether a window is open at now and when it closes.
+// Occurrences that start before the previous one closes extend the window.
+func windowOpenUntil(schedule cron.Schedule, duration time.Duration, now time.Time) (time.Time, bool) {
+	start := schedule.Next(now.Add(-duration))
+	if start.IsZero() || start.After(now) {
+		return time.Time{}, false
+	}
+
+	until := start.Add(duration)
+	for i := 0; i < maxWindowChain; i++ {
+		next := schedule.Next(start)
+		if next.IsZero() || next.After(until) {
+			break
+		}
+		start = next
+		until = next.Add(duration)
+	}
+
+	return until, true
+}
+
+// requeueAfter is how long until the maintenance state of an aquarium changes
+// on its own, zero when it won't.
+func (m maintenanceState) requeueAfter(now time.Time) time.Duration {
+	switch {
+	case m.Err != nil:
+		return 0
+	case m.Window != "":
+		return m.Until.Sub(now)
+	case !m.Next.IsZero():
+		return m.Next.Sub(now)
+	}
+
+	return 0
+}
+
+// maintenanceTanks is the number of tanks kept running during maintenance,
+// never more than the aquarium would run otherwise.
+func maintenanceTanks(aquarium *funv1alpha1.Aquarium) int32 {
+	floor := int32(0)
+	if tanks := aquarium.Spec.Maintenance.Tanks; tanks != nil {
+		floor = *tanks
+	}
+
+	if limit := tankCapacity(aquarium); floor > limit {
+		return limit
+	}
+
+	return floor
+}
+
+// setMaintenanceCondition reports the maintenance state of an aquarium and
+// emits an event when maintenance starts or ends.
+func (r *AquariumReconciler) setMaintenanceCondition(aquarium *funv1alpha1.Aquarium, state maintenanceState) {
+	wasInMaintenance := apimeta.IsStatusConditionTrue(aquarium.Status.Conditions, InMaintenance)
+
+	switch {
+	case state.Paused:
+		setCondition(aquarium, InMaintenance, metav1.ConditionTrue, ReconciliationPaused,
+			fmt.Sprintf("The tanks are left alone while the %s annotation is set", PausedAnnotation))
+	case state.Window != "":
+		setCondition(aquarium, InMaintenance, metav1.ConditionTrue, MaintenanceWindowOpen,
+			fmt.Sprintf("Maintenance window %s is open until %s, %d tanks are kept running",
+				state.Window, state.Until.Format(time.RFC3339), maintenanceTanks(aquarium)))
+	case state.Err != nil:
+		setCondition(aquarium, InMaintenance, metav1.ConditionFalse, InvalidMaintenance, state.Err.Error())
+	case !state.Next.IsZero():
+		setCondition(aquarium, InMaintenance, metav1.ConditionFalse, NoMaintenance,
+			fmt.Sprintf("The next maintenance window opens at %s", state.Next.Format(time.RFC3339)))
+	default:
+		setCondition(aquarium, InMaintenance, metav1.ConditionFalse, NoMaintenance,
+			"No maintenance is scheduled")
+	}
+
+	condition := apimeta.FindStatusCondition(aquarium.Status.Conditions, InMaintenance)
+	switch {
+	case !wasInMaintenance && state.active():
+		r.events.Eventf(aquarium, corev1.EventTypeNormal, MaintenanceStarted, "%s", condition.Message)
+	case wasInMaintenance && !state.active():
+		r.events.Eventf(aquarium, corev1.EventTypeNormal, MaintenanceEnded, "Maintenance is over, the tanks resume")
+	}
+}
+
+// applyMaintenance keeps unhealthy fish Kinda healthy while the tanks are
+// under maintenance, since fewer ready tanks are expected then. Healthy fish
+// stay healthy.
+func applyMaintenance(report healthReport, state maintenanceState) healthReport {
+	if !state.active() || report.Health != funv1alpha1.Unhealthy {
+		return report
+	}
+
+	report.Health = funv1alpha1.KindOfHealthy
+	report.Status = metav1.ConditionFalse
+	if state.Paused {
+		report.Reason = ReconciliationPaused
+		report.Message = "The aquarium is paused for maintenance"
+	} else {
+		report.Reason = MaintenanceWindowOpen
+		report.Message = fmt.Sprintf("The aquarium is in maintenance window %s", state.Window)
+	}
+
+	return report
+}
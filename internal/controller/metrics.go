@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+var (
+	tanksDesired = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aquarium_tanks_desired",
+			Help: "Number of tanks an aquarium should be running",
+		},
+		[]string{"namespace", "aquarium", "location"},
+	)
+
+	tanksReady = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aquarium_tanks_ready",
+			Help: "Number of ready tanks of an aquarium",
+		},
+		[]string{"namespace", "aquarium", "location"},
+	)
+
+	fishHealth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aquarium_fish_health",
+			Help: "Fish health of an aquarium, 1 for the current health and 0 otherwise",
+		},
+		[]string{"namespace", "aquarium", "location", "health"},
+	)
+
+	healthTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "aquarium_fish_health_transitions_total",
+			Help: "Number of times the fish health of an aquarium changed",
+		},
+		[]string{"namespace", "aquarium", "location", "from", "to"},
+	)
+
+	timeToHealthy = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "aquarium_time_to_healthy_seconds",
+			Help:    "Time from a change of an aquarium's spec until all of its tanks are ready again",
+			Buckets: prometheus.ExponentialBuckets(5, 2, 10),
+		},
+		[]string{"location"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(tanksDesired, tanksReady, fishHealth, healthTransitions, timeToHealthy)
+}
+
+var fishHealthValues = []funv1alpha1.FishHealth{
+	funv1alpha1.Healthy,
+	funv1alpha1.KindOfHealthy,
+	funv1alpha1.Unhealthy,
+	funv1alpha1.Unknown,
+}
+
+// specChange is the generation of an aquarium's spec and when the operator first saw it.
+type specChange struct {
+	uid        types.UID
+	generation int64
+	since      time.Time
+	// healed is set once the tanks were healthy after the change.
+	healed bool
+}
+
+// fleetMetrics keeps the aquarium metrics up to date.
+type fleetMetrics struct {
+	mu      sync.Mutex
+	changes map[types.NamespacedName]*specChange
+}
+
+func newFleetMetrics() *fleetMetrics {
+	return &fleetMetrics{changes: map[types.NamespacedName]*specChange{}}
+}
+
+// record updates the metrics of an aquarium from its freshly computed status,
+// so it must be called once the effective tanks are known.
+// previous is the fish health the aquarium had before this reconcile.
+func (m *fleetMetrics) record(
+	aquarium *funv1alpha1.Aquarium,
+	deploy *appsv1.Deployment,
+	previous funv1alpha1.FishHealth,
+	report healthReport,
+) {
+	ns, name, location := aquarium.Namespace, aquarium.Name, aquarium.Spec.Location
+
+	// The effective tanks account for maintenance windows and paused aquaria.
+	tanksDesired.WithLabelValues(ns, name, location).Set(float64(aquarium.Status.EffectiveTanks))
+	tanksReady.WithLabelValues(ns, name, location).Set(float64(aquarium.Status.NumTanksReady))
+	for _, health := range fishHealthValues {
+		value := 0.0
+		if health == report.Health {
+			value = 1
+		}
+		fishHealth.WithLabelValues(ns, name, location, string(health)).Set(value)
+	}
+
+	if previous != "" && previous != report.Health {
+		healthTransitions.WithLabelValues(ns, name, location, string(previous), string(report.Health)).Inc()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: ns, Name: name}
+	change, ok := m.changes[key]
+	if !ok || change.uid != aquarium.UID || change.generation != aquarium.Generation {
+		change = &specChange{uid: aquarium.UID, generation: aquarium.Generation, since: time.Now()}
+		m.changes[key] = change
+	}
+
+	// A rollout that hasn't started yet still reports the old tanks as ready.
+	if !change.healed && report.Health == funv1alpha1.Healthy && !rolloutProgressing(deploy) {
+		change.healed = true
+		timeToHealthy.WithLabelValues(location).Observe(time.Since(change.since).Seconds())
+	}
+}
+
+// forget removes the metrics of a deleted aquarium.
+func (m *fleetMetrics) forget(namespace, name string) {
+	labels := prometheus.Labels{"namespace": namespace, "aquarium": name}
+	tanksDesired.DeletePartialMatch(labels)
+	tanksReady.DeletePartialMatch(labels)
+	fishHealth.DeletePartialMatch(labels)
+	healthTransitions.DeletePartialMatch(labels)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.changes, types.NamespacedName{Namespace: namespace, Name: name})
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// migrateDeployment replaces a Deployment whose selector no longer matches the
+// selector labels of the aquarium's stable tanks, like the legacy selector or
+// the one used before canary tanks were told apart. Selectors are immutable, so
+// the Deployment is deleted with orphan propagation and its ReplicaSets are
+// adopted by the aquarium. The old tanks keep running until the new Deployment
+// is available and cleanupLegacyReplicaSets removes them. Only Deployments for
+// which isTanksDeployment holds may be migrated.
+//
+// It returns true when the Deployment was deleted and must be recreated.
+func (r *AquariumReconciler) migrateDeployment(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	deploy *appsv1.Deployment,
+) (bool, error) {
+	desired := &metav1.LabelSelector{MatchLabels: trackSelectorLabels(aquarium, StableTrack)}
+	if deploy.Spec.Selector != nil && equality.Semantic.DeepEqual(deploy.Spec.Selector, desired) {
+		return false, nil
+	}
+
+	log := log.FromContext(ctx)
+	log.Info("migrating deployment to a new selector", "deployment", deploy.Name)
+	r.events.Eventf(aquarium, corev1.EventTypeNormal, DeploymentMigrating,
+		"Recreating deployment %s with a selector unique to the aquarium", deploy.Name)
+
+	var replicaSets appsv1.ReplicaSetList
+	if err := r.List(ctx, &replicaSets, client.InNamespace(deploy.Namespace)); err != nil {
+		return false, fmt.Errorf("failed to list replica sets: %w", err)
+	}
+
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if owner := metav1.GetControllerOf(rs); owner == nil || owner.UID != deploy.UID {
+			continue
+		}
+
+		if err := r.adoptReplicaSet(ctx, aquarium, rs); err != nil {
+			return false, err
+		}
+	}
+
+	if err := r.Delete(
+		ctx,
+		deploy,
+		client.PropagationPolicy(metav1.DeletePropagationOrphan),
+		client.Preconditions{UID: &deploy.UID},
+	); client.IgnoreNotFound(err) != nil {
+		return false, fmt.Errorf("failed to delete deployment %s: %w", deploy.Name, err)
+	}
+
+	return true, nil
+}
+
+// adoptReplicaSet adds the aquarium as a non-controller owner of a ReplicaSet
+// so it survives the orphaning of its Deployment and is collected with the aquarium.
+// The legacy label is swapped for LegacyTanksKey, so the legacy Deployments of
+// other aquaria in the namespace, which select every ReplicaSet with the legacy
+// label, don't claim the orphaned ReplicaSet and scale it down.
+func (r *AquariumReconciler) adoptReplicaSet(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	rs *appsv1.ReplicaSet,
+) error {
+	_, legacy := rs.Labels[AppKey]
+	if isOwnedBy(rs, aquarium) && !legacy {
+		return nil
+	}
+
+	patch := client.MergeFrom(rs.DeepCopy())
+	if !isOwnedBy(rs, aquarium) {
+		owner := ownerReference(aquarium)
+		owner.Controller = nil
+		rs.OwnerReferences = append(rs.OwnerReferences, owner)
+	}
+	if rs.Labels == nil {
+		rs.Labels = map[string]string{}
+	}
+	delete(rs.Labels, AppKey)
+	rs.Labels[LegacyTanksKey] = string(aquarium.UID)
+
+	if err := r.Patch(ctx, rs, patch); err != nil {
+		return fmt.Errorf("failed to adopt replica set %s: %w", rs.Name, err)
+	}
+
+	return nil
+}
+
+// cleanupLegacyReplicaSets deletes ReplicaSets adopted by migrateDeployment once
+// the aquarium's new Deployment has all of its tanks available.
+func (r *AquariumReconciler) cleanupLegacyReplicaSets(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	deploy *appsv1.Deployment,
+) error {
+	if deploy.Spec.Replicas == nil || deploy.Status.AvailableReplicas < *deploy.Spec.Replicas {
+		return nil
+	}
+
+	replicaSets, err := r.legacyReplicaSets(ctx, aquarium)
+	if err != nil {
+		return err
+	}
+
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		if metav1.GetControllerOf(rs) != nil || !isOwnedBy(rs, aquarium) {
+			continue
+		}
+
+		log.FromContext(ctx).Info("deleting legacy replica set", "replicaSet", rs.Name)
+		if err := r.Delete(
+			ctx,
+			rs,
+			client.PropagationPolicy(metav1.DeletePropagationBackground),
+		); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete legacy replica set %s: %w", rs.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// legacyReplicaSets lists the ReplicaSets that may run legacy tanks of an
+// aquarium: those adopted by it, and those still carrying the legacy label.
+func (r *AquariumReconciler) legacyReplicaSets(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+) ([]appsv1.ReplicaSet, error) {
+	var adopted, labeled appsv1.ReplicaSetList
+	if err := r.List(
+		ctx,
+		&adopted,
+		client.InNamespace(aquarium.Namespace),
+		client.MatchingLabels{LegacyTanksKey: string(aquarium.UID)},
+	); err != nil {
+		return nil, fmt.Errorf("failed to list adopted replica sets: %w", err)
+	}
+	if err := r.List(
+		ctx,
+		&labeled,
+		client.InNamespace(aquarium.Namespace),
+		client.MatchingLabels{AppKey: AquariumValue},
+	); err != nil {
+		return nil, fmt.Errorf("failed to list legacy replica sets: %w", err)
+	}
+
+	replicaSets := adopted.Items
+	for i := range labeled.Items {
+		if labeled.Items[i].Labels[LegacyTanksKey] != string(aquarium.UID) {
+			replicaSets = append(replicaSets, labeled.Items[i])
+		}
+	}
+
+	return replicaSets, nil
+}
+
+// isTanksDeployment tells whether a Deployment named after an aquarium runs
+// its tanks: it is controlled by the aquarium, or carries the legacy label of
+// the tanks from before the aquarium controlled its Deployment.
+func isTanksDeployment(deploy *appsv1.Deployment, aquarium *funv1alpha1.Aquarium) bool {
+	return metav1.IsControlledBy(deploy, aquarium) || deploy.Labels[AppKey] == AquariumValue
+}
+
+func isOwnedBy(obj metav1.Object, aquarium *funv1alpha1.Aquarium) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == aquarium.UID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// placement is the outcome of placing fish in an aquarium.
+type placement struct {
+	Placed  bool
+	Reason  string
+	Message string
+}
+
+// placeFish decides whether fish can live in an aquarium. aquarium is nil when
+// the aquarium the fish reference doesn't exist.
+func placeFish(fish *funv1alpha1.Fish, aquarium *funv1alpha1.Aquarium) placement {
+	if aquarium == nil {
+		return placement{
+			Reason:  AquariumNotFound,
+			Message: fmt.Sprintf("Aquarium %s does not exist", fish.Spec.Aquarium),
+		}
+	}
+
+	if !aquarium.DeletionTimestamp.IsZero() {
+		return placement{
+			Reason:  AquariumClosing,
+			Message: fmt.Sprintf("Aquarium %s is closing", aquarium.Name),
+		}
+	}
+
+	if tank := fish.Spec.Tank; tank != nil && *tank >= tankCapacity(aquarium) {
+		return placement{
+			Reason: TankNotFound,
+			Message: fmt.Sprintf("Aquarium %s has no tank %d, it has %d tanks",
+				aquarium.Name, *tank, tankCapacity(aquarium)),
+		}
+	}
+
+	return placement{
+		Placed:  true,
+		Reason:  FishPlaced,
+		Message: fmt.Sprintf("%d %s live in aquarium %s", fish.Spec.Count, fish.Spec.Species, aquarium.Name),
+	}
+}
+
+// tankCapacity is the number of tanks fish can be placed in. Aquaria can hold
+// fish in every tank they may scale up to, by autoscaling or on schedule.
+func tankCapacity(aquarium *funv1alpha1.Aquarium) int32 {
+	if aquarium.Spec.Autoscaling != nil {
+		return aquarium.Spec.Autoscaling.MaxTanks
+	}
+
+	return scheduledTanks(aquarium)
+}
+
+// PlacedFish returns the fish that are placed in the aquarium.
+func PlacedFish(aquarium *funv1alpha1.Aquarium, fish []funv1alpha1.Fish) []funv1alpha1.Fish {
+	var placed []funv1alpha1.Fish
+	for i := range fish {
+		if fish[i].Spec.Aquarium == aquarium.Name && placeFish(&fish[i], aquarium).Placed {
+			placed = append(placed, fish[i])
+		}
+	}
+
+	return placed
+}
+
+// reconcileFish sums up the fish placed in an aquarium and checks that they
+// can live together.
+func (r *AquariumReconciler) reconcileFish(ctx context.Context, aquarium *funv1alpha1.Aquarium) error {
+	var fish funv1alpha1.FishList
+	if err := r.List(
+		ctx,
+		&fish,
+		client.InNamespace(aquarium.Namespace),
+		client.MatchingFields{fishAquariumIndexKey: aquarium.Name},
+	); err != nil {
+		return fmt.Errorf("failed to list fish: %w", err)
+	}
+
+	placed := PlacedFish(aquarium, fish.Items)
+	aquarium.Status.Population = summarizePopulation(placed)
+
+	return r.setCompatibilityCondition(ctx, aquarium, placed)
+}
+
+// summarizePopulation counts fish per species.
+func summarizePopulation(fish []funv1alpha1.Fish) funv1alpha1.Population {
+	var population funv1alpha1.Population
+
+	perSpecies := map[string]int32{}
+	for i := range fish {
+		perSpecies[fish[i].Spec.Species] += fish[i].Spec.Count
+		population.TotalFish += fish[i].Spec.Count
+	}
+
+	for species, count := range perSpecies {
+		population.Species = append(population.Species, funv1alpha1.SpeciesCount{Species: species, Count: count})
+	}
+	sort.Slice(population.Species, func(i, j int) bool {
+		return population.Species[i].Species < population.Species[j].Species
+	})
+
+	return population
+}
+
+// enqueueReferencedAquarium enqueues the aquarium an object references by
+// name. When the reference changes, the aquarium it left is enqueued as well.
+func enqueueReferencedAquarium(aquariumOf func(client.Object) string) handler.Funcs {
+	enqueue := func(obj client.Object, q workqueue.RateLimitingInterface) {
+		if name := aquariumOf(obj); name != "" {
+			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: obj.GetNamespace(),
+				Name:      name,
+			}})
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(_ context.Context, e event.CreateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+		UpdateFunc: func(_ context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.ObjectOld, q)
+			enqueue(e.ObjectNew, q)
+		},
+		DeleteFunc: func(_ context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+		GenericFunc: func(_ context.Context, e event.GenericEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+	}
+}
+
+// fishAquarium returns the aquarium fish live in.
+func fishAquarium(obj client.Object) string {
+	if fish, ok := obj.(*funv1alpha1.Fish); ok {
+		return fish.Spec.Aquarium
+	}
+
+	return ""
+}
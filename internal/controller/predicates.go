@@ -1,10 +1,36 @@
 package controller
 
 import (
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
+// AquariumLabelPredicate matches objects managed by the operator. Objects
+// carrying the legacy app label are matched too so they can be migrated.
 var AquariumLabelPredicate = predicate.NewPredicateFuncs(func(o client.Object) bool {
-	return o.GetLabels()[AppKey] == AquariumValue
+	labels := o.GetLabels()
+	return labels[AppManagedByKey] == AquariumOperator || labels[AppKey] == AquariumValue
 })
+
+// NodeSchedulingChangedPredicate matches node updates that can change which
+// tanks may be scheduled onto the node, ignoring the frequent status updates.
+var NodeSchedulingChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldNode, ok := e.ObjectOld.(*corev1.Node)
+		if !ok {
+			return false
+		}
+		newNode, ok := e.ObjectNew.(*corev1.Node)
+		if !ok {
+			return false
+		}
+
+		return !reflect.DeepEqual(oldNode.Labels, newNode.Labels) ||
+			!reflect.DeepEqual(oldNode.Spec.Taints, newNode.Spec.Taints) ||
+			oldNode.Spec.Unschedulable != newNode.Spec.Unschedulable
+	},
+}
@@ -0,0 +1,224 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// applyRolloutStrategy sets the strategy the tanks' Deployment rolls out new
+// templates with. Rolling updates without limits keep the API defaults.
+func applyRolloutStrategy(deploy *appsv1.Deployment, rollout funv1alpha1.RolloutSpec) {
+	if rollout.Strategy == funv1alpha1.RolloutRecreate {
+		deploy.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+		return
+	}
+
+	if rollout.MaxSurge != nil || rollout.MaxUnavailable != nil {
+		deploy.Spec.Strategy = appsv1.DeploymentStrategy{
+			Type: appsv1.RollingUpdateDeploymentStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDeployment{
+				MaxSurge:       rollout.MaxSurge,
+				MaxUnavailable: rollout.MaxUnavailable,
+			},
+		}
+	}
+}
+
+// setTemplateHash annotates a desired Deployment with a hash of its pod
+// template, so a canary rollout can tell when the template changes.
+func setTemplateHash(deploy *appsv1.Deployment) error {
+	data, err := json.Marshal(deploy.Spec.Template)
+	if err != nil {
+		return fmt.Errorf("failed to hash pod template: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	if deploy.Annotations == nil {
+		deploy.Annotations = map[string]string{}
+	}
+	deploy.Annotations[TemplateHashAnnotation] = hex.EncodeToString(sum[:8])
+
+	return nil
+}
+
+// canaryName names the Deployment of the canary tanks of an aquarium.
+func canaryName(aquarium *funv1alpha1.Aquarium) string {
+	return aquarium.Name + "-" + CanaryTrack
+}
+
+// splitCanary holds back a new template during a canary rollout. The desired
+// Deployment keeps the template of the live one, and the returned canary
+// Deployment runs the new template in up to canary_tanks of the tanks. The
+// canary is nil when nothing is held back: the aquarium doesn't roll out
+// canaries, the template didn't change, or the new template was promoted.
+func splitCanary(aquarium *funv1alpha1.Aquarium, live, desired *appsv1.Deployment) *appsv1.Deployment {
+	if aquarium.Spec.Rollout.Strategy != funv1alpha1.RolloutCanary || live == nil ||
+		aquarium.Annotations[PromoteAnnotation] == "true" {
+		return nil
+	}
+
+	// Deployments applied before canaries existed have no hash to compare.
+	stableHash := live.Annotations[TemplateHashAnnotation]
+	if stableHash == "" || stableHash == desired.Annotations[TemplateHashAnnotation] {
+		return nil
+	}
+
+	canary := desired.DeepCopy()
+	canary.Name = canaryName(aquarium)
+	delete(canary.Annotations, DesiredStateAnnotation)
+	canary.Labels[TrackKey] = CanaryTrack
+	canary.Spec.Selector.MatchLabels[TrackKey] = CanaryTrack
+	canary.Spec.Template.Labels[TrackKey] = CanaryTrack
+
+	tanks := funv1alpha1.DefaultCanaryTanks
+	if aquarium.Spec.Rollout.CanaryTanks != nil {
+		tanks = *aquarium.Spec.Rollout.CanaryTanks
+	}
+	if desired.Spec.Replicas != nil {
+		if tanks > *desired.Spec.Replicas {
+			tanks = *desired.Spec.Replicas
+		}
+		*desired.Spec.Replicas -= tanks
+	}
+	canary.Spec.Replicas = &tanks
+
+	desired.Spec.Template = *live.Spec.Template.DeepCopy()
+	desired.Annotations[TemplateHashAnnotation] = stableHash
+
+	return canary
+}
+
+// reconcileCanary applies the canary Deployment of an aquarium, or deletes it
+// when there is no canary. A promotion is acknowledged by removing the promote
+// annotation once the canary is gone. It returns the applied canary.
+func (r *AquariumReconciler) reconcileCanary(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	canary *appsv1.Deployment,
+) (*appsv1.Deployment, error) {
+	var live appsv1.Deployment
+	err := r.Get(ctx, types.NamespacedName{Namespace: aquarium.Namespace, Name: canaryName(aquarium)}, &live)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+	exists := err == nil
+	if exists && !metav1.IsControlledBy(&live, aquarium) {
+		return nil, fmt.Errorf("deployment %s already exists and is not owned by the aquarium", live.Name)
+	}
+
+	if canary != nil {
+		if err := r.Patch(
+			ctx,
+			canary,
+			client.Apply,
+			client.ForceOwnership,
+			client.FieldOwner(AquariumOperator),
+		); err != nil {
+			return nil, fmt.Errorf("failed to apply canary deployment: %w", err)
+		}
+		if !exists {
+			r.events.Eventf(aquarium, corev1.EventTypeNormal, CanaryStarted,
+				"Rolling out the new tank template to %d canary tanks until it is promoted", *canary.Spec.Replicas)
+		}
+
+		return canary, nil
+	}
+
+	if exists && live.DeletionTimestamp.IsZero() {
+		log.FromContext(ctx).Info("deleting canary deployment", "deployment", live.Name)
+		if err := r.Delete(ctx, &live); client.IgnoreNotFound(err) != nil {
+			return nil, fmt.Errorf("failed to delete canary deployment: %w", err)
+		}
+		if aquarium.Annotations[PromoteAnnotation] == "true" {
+			r.events.Eventf(aquarium, corev1.EventTypeNormal, CanaryPromoted,
+				"Promoted the tank template of the canary to every tank")
+		}
+	}
+
+	if _, ok := aquarium.Annotations[PromoteAnnotation]; ok {
+		// Patch a copy, so the status gathered so far isn't overwritten.
+		promoted := aquarium.DeepCopy()
+		patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, PromoteAnnotation))
+		if err := r.Patch(ctx, promoted, client.RawPatch(types.MergePatchType, patch)); err != nil {
+			return nil, fmt.Errorf("failed to acknowledge promotion: %w", err)
+		}
+		aquarium.ResourceVersion = promoted.ResourceVersion
+		delete(aquarium.Annotations, PromoteAnnotation)
+	}
+
+	return nil, nil
+}
+
+// withCanary judges the canary tanks together with the other tanks.
+func withCanary(deploy, canary *appsv1.Deployment) *appsv1.Deployment {
+	if deploy == nil || canary == nil {
+		return deploy
+	}
+
+	combined := deploy.DeepCopy()
+	if combined.Spec.Replicas != nil && canary.Spec.Replicas != nil {
+		*combined.Spec.Replicas += *canary.Spec.Replicas
+	}
+	combined.Status.Replicas += canary.Status.Replicas
+	combined.Status.UpdatedReplicas += canary.Status.UpdatedReplicas
+	combined.Status.ReadyReplicas += canary.Status.ReadyReplicas
+	combined.Status.AvailableReplicas += canary.Status.AvailableReplicas
+	combined.Status.UnavailableReplicas += canary.Status.UnavailableReplicas
+
+	return combined
+}
+
+// rolloutStatus counts the tanks running the latest and older templates. While
+// a canary is held back, the other tanks all run an older template.
+func rolloutStatus(deploy, canary *appsv1.Deployment) funv1alpha1.RolloutStatus {
+	if deploy == nil {
+		return funv1alpha1.RolloutStatus{}
+	}
+
+	if canary != nil {
+		return funv1alpha1.RolloutStatus{
+			NewTemplateTanks:  canary.Status.Replicas,
+			OldTemplateTanks:  deploy.Status.Replicas,
+			AwaitingPromotion: true,
+		}
+	}
+
+	status := funv1alpha1.RolloutStatus{
+		NewTemplateTanks: deploy.Status.UpdatedReplicas,
+		OldTemplateTanks: deploy.Status.Replicas - deploy.Status.UpdatedReplicas,
+	}
+	if status.OldTemplateTanks < 0 {
+		status.OldTemplateTanks = 0
+	}
+
+	return status
+}
+
+// clearRollingUpdate removes the rolling update parameters the API defaulted
+// on a Deployment before it is switched to Recreate. Nobody owns them, so
+// applying the Recreate strategy alone would leave them behind and be rejected.
+func (r *AquariumReconciler) clearRollingUpdate(ctx context.Context, live, desired *appsv1.Deployment) error {
+	if live == nil || live.Spec.Strategy.RollingUpdate == nil ||
+		desired.Spec.Strategy.Type != appsv1.RecreateDeploymentStrategyType {
+		return nil
+	}
+
+	patch := []byte(`{"spec":{"strategy":{"type":"Recreate","rollingUpdate":null}}}`)
+	if err := r.Patch(ctx, live, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return fmt.Errorf("failed to clear rolling update of deployment %s: %w", live.Name, err)
+	}
+
+	return nil
+}
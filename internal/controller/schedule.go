@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/utils/pointer"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// scheduleState is the number of tanks the schedule of an aquarium asks for
+// and when that changes next.
+type scheduleState struct {
+	// Tanks is the number of tanks in effect.
+	Tanks int32
+	// Entry is the name of the active entry, empty when num_tanks is in effect.
+	Entry string
+	// Next is when an entry next starts or ends, zero when none will.
+	Next time.Time
+	// Err reports entries that can't be parsed. They are skipped.
+	Err error
+}
+
+// evaluateSchedule works out which schedule entry of an aquarium is in effect
+// at now. The schedule is ignored while autoscaling.
+func evaluateSchedule(aquarium *funv1alpha1.Aquarium, now time.Time) scheduleState {
+	state := scheduleState{Tanks: aquarium.Spec.NumTanks}
+	if aquarium.Spec.Autoscaling != nil {
+		return state
+	}
+
+	boundary := func(t time.Time) {
+		if !t.IsZero() && (state.Next.IsZero() || t.Before(state.Next)) {
+			state.Next = t
+		}
+	}
+
+	var errs []error
+	for _, entry := range aquarium.Spec.Schedule {
+		start, err := ParseCron(entry.Start, entry.TimeZone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule entry %s has an invalid start: %w", entry.Name, err))
+			continue
+		}
+		if entry.Duration.Duration <= 0 {
+			continue
+		}
+
+		boundary(start.Next(now))
+		until, active := windowOpenUntil(start, entry.Duration.Duration, now)
+		if !active {
+			continue
+		}
+		boundary(until)
+
+		if state.Entry == "" {
+			state.Entry = entry.Name
+			state.Tanks = entry.Tanks
+		}
+	}
+	state.Err = errors.Join(errs...)
+
+	return state
+}
+
+// scheduledTanks is the most tanks the schedule of an aquarium ever asks for.
+func scheduledTanks(aquarium *funv1alpha1.Aquarium) int32 {
+	tanks := aquarium.Spec.NumTanks
+	for _, entry := range aquarium.Spec.Schedule {
+		if entry.Tanks > tanks {
+			tanks = entry.Tanks
+		}
+	}
+
+	return tanks
+}
+
+// desiredReplicas is the number of tanks applied to the Deployment. It is nil
+// while the autoscaler owns the replicas, so the two don't fight over them,
+// and while the aquarium is paused.
+func desiredReplicas(aquarium *funv1alpha1.Aquarium, maintenance maintenanceState, schedule scheduleState) *int32 {
+	if maintenance.Paused {
+		return nil
+	}
+
+	if maintenance.Window != "" {
+		tanks := maintenanceTanks(aquarium)
+		if aquarium.Spec.Autoscaling == nil && schedule.Tanks < tanks {
+			tanks = schedule.Tanks
+		}
+		return pointer.Int32(tanks)
+	}
+
+	if aquarium.Spec.Autoscaling != nil {
+		return nil
+	}
+
+	return pointer.Int32(schedule.Tanks)
+}
+
+// effectiveTanks is the number of tanks asked of the Deployment. Without
+// desired replicas that is whatever the autoscaler, or whoever paused the
+// aquarium, set on the Deployment.
+func effectiveTanks(replicas *int32, deploy *appsv1.Deployment) int32 {
+	if replicas != nil {
+		return *replicas
+	}
+
+	if deploy != nil && deploy.Spec.Replicas != nil {
+		return *deploy.Spec.Replicas
+	}
+
+	return 0
+}
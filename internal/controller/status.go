@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// applyStatus writes the status of an aquarium with server-side apply as the
+// operator. Only the conditions the operator owns are applied, so other
+// controllers can own conditions, and status fields the operator doesn't
+// set, next to it. The apply is bound to the UID of the aquarium so the
+// status of a recreated aquarium isn't overwritten.
+func (r *AquariumReconciler) applyStatus(ctx context.Context, aquarium *funv1alpha1.Aquarium) error {
+	status := aquarium.Status.DeepCopy()
+	status.Conditions = ownedConditions(status.Conditions)
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return err
+	}
+
+	patch := &unstructured.Unstructured{Object: map[string]interface{}{"status": content}}
+	patch.SetGroupVersionKind(funv1alpha1.GroupVersion.WithKind("Aquarium"))
+	patch.SetName(aquarium.Name)
+	patch.SetNamespace(aquarium.Namespace)
+	patch.SetUID(aquarium.UID)
+
+	return r.Status().Patch(ctx, patch, client.Apply, client.FieldOwner(AquariumOperator), client.ForceOwnership)
+}
+
+// ownedConditions returns the conditions owned by the operator.
+func ownedConditions(conditions []metav1.Condition) []metav1.Condition {
+	var owned []metav1.Condition
+	for _, condition := range conditions {
+		for _, conditionType := range aquariumConditionTypes {
+			if condition.Type == conditionType {
+				owned = append(owned, condition)
+				break
+			}
+		}
+	}
+
+	return owned
+}
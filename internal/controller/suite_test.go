@@ -34,6 +34,7 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -59,6 +60,8 @@ var (
 const (
 	envtestVers       = "1.27.1"
 	AquariumNamespace = "aquarium"
+	// TankImage is the default tank image the operator is configured with.
+	TankImage = "registry.example.com/aquarium/tank:v1"
 )
 
 func TestControllers(t *testing.T) {
@@ -93,10 +96,36 @@ var _ = BeforeSuite(func() {
 
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: scheme.Scheme,
+		Cache:  cache.Options{ByObject: controller.CacheByObject()},
 	})
 	Expect(err).NotTo(HaveOccurred())
 
+	err = controller.SetupFieldIndexes(ctx, mgr)
+	Expect(err).NotTo(HaveOccurred())
+
 	err = (&controller.AquariumReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("aquarium-controller"),
+
+		APIReader:        mgr.GetAPIReader(),
+		DefaultTankImage: TankImage,
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = (&controller.FishReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = (&controller.FeedingScheduleReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = (&controller.ExhibitReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr)
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// CacheByObject limits what a manager caches of the objects the controllers
+// watch. Only the pods of tanks are cached, rather than every pod in the
+// cluster; the legacy tanks are listed through AquariumReconciler.APIReader.
+func CacheByObject() map[client.Object]cache.ByObject {
+	return map[client.Object]cache.ByObject{
+		&corev1.Pod{}: {Label: labels.SelectorFromSet(labels.Set{AppNameKey: AquariumAppName})},
+	}
+}
+
+// TankStatusChangedPredicate matches pod updates that change how a tank is
+// reported, ignoring the other status updates of the pod.
+var TankStatusChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, ok := e.ObjectOld.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+		newPod, ok := e.ObjectNew.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+
+		return tankStatus(oldPod) != tankStatus(newPod)
+	},
+}
+
+// reconcileTanks reports on the pods running the tanks of an aquarium.
+func (r *AquariumReconciler) reconcileTanks(ctx context.Context, aquarium *funv1alpha1.Aquarium) error {
+	var pods corev1.PodList
+	if err := r.List(
+		ctx,
+		&pods,
+		client.InNamespace(aquarium.Namespace),
+		client.MatchingLabels(selectorLabels(aquarium)),
+	); err != nil {
+		return fmt.Errorf("failed to list tanks: %w", err)
+	}
+
+	aquarium.Status.Tanks = summarizeTanks(pods.Items)
+
+	return nil
+}
+
+// summarizeTanks reports on the tanks running in pods, leaving out pods that
+// are going away. Tanks that aren't ready come first, so they are listed even
+// when there are more tanks than MaxTankStatuses.
+func summarizeTanks(pods []corev1.Pod) []funv1alpha1.TankStatus {
+	tanks := make([]funv1alpha1.TankStatus, 0, len(pods))
+	for i := range pods {
+		if pods[i].DeletionTimestamp.IsZero() {
+			tanks = append(tanks, tankStatus(&pods[i]))
+		}
+	}
+
+	sort.Slice(tanks, func(i, j int) bool {
+		if tanks[i].Ready != tanks[j].Ready {
+			return !tanks[i].Ready
+		}
+		return tanks[i].Name < tanks[j].Name
+	})
+
+	if len(tanks) > funv1alpha1.MaxTankStatuses {
+		tanks = tanks[:funv1alpha1.MaxTankStatuses]
+	}
+	if len(tanks) == 0 {
+		return nil
+	}
+
+	return tanks
+}
+
+// tankStatus reports on the tank running in a pod.
+func tankStatus(pod *corev1.Pod) funv1alpha1.TankStatus {
+	tank := funv1alpha1.TankStatus{
+		Name:  pod.Name,
+		Node:  pod.Spec.NodeName,
+		Phase: pod.Status.Phase,
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			tank.Ready = condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	for _, container := range pod.Status.ContainerStatuses {
+		if container.Name != TankContainerName {
+			continue
+		}
+
+		tank.RestartCount = container.RestartCount
+		if terminated := container.LastTerminationState.Terminated; terminated != nil {
+			tank.LastTerminationReason = terminated.Reason
+		}
+	}
+
+	return tank
+}
+
+// tankAquarium maps the pod of a tank to its aquarium.
+func tankAquarium(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	if labels[AppNameKey] != AquariumAppName || labels[AppInstanceKey] == "" {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: obj.GetNamespace(),
+		Name:      labels[AppInstanceKey],
+	}}}
+}
@@ -0,0 +1,301 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// waterQualityReport is the outcome of judging the water readings of an aquarium.
+type waterQualityReport struct {
+	Status  metav1.ConditionStatus
+	Reason  string
+	Message string
+	// Expires is when the oldest reading judged leaves the window, nil
+	// without readings.
+	Expires *time.Time
+}
+
+// waterParameter is a measurement of the water judged against a range.
+type waterParameter struct {
+	name  string
+	unit  string
+	value func(*funv1alpha1.WaterReadingSpec) *resource.Quantity
+	limit func(*funv1alpha1.WaterQualitySpec) *funv1alpha1.Range
+}
+
+var waterParameters = []waterParameter{
+	{
+		name:  "pH",
+		value: func(r *funv1alpha1.WaterReadingSpec) *resource.Quantity { return r.PH },
+		limit: func(q *funv1alpha1.WaterQualitySpec) *funv1alpha1.Range { return q.PH },
+	},
+	{
+		name:  "temperature",
+		unit:  "°C",
+		value: func(r *funv1alpha1.WaterReadingSpec) *resource.Quantity { return r.TemperatureCelsius },
+		limit: func(q *funv1alpha1.WaterQualitySpec) *funv1alpha1.Range { return q.TemperatureCelsius },
+	},
+	{
+		name:  "ammonia",
+		unit:  " ppm",
+		value: func(r *funv1alpha1.WaterReadingSpec) *resource.Quantity { return r.AmmoniaPPM },
+		limit: func(q *funv1alpha1.WaterQualitySpec) *funv1alpha1.Range { return q.AmmoniaPPM },
+	},
+	{
+		name:  "nitrate",
+		unit:  " ppm",
+		value: func(r *funv1alpha1.WaterReadingSpec) *resource.Quantity { return r.NitratePPM },
+		limit: func(q *funv1alpha1.WaterQualitySpec) *funv1alpha1.Range { return q.NitratePPM },
+	},
+}
+
+// reconcileWaterQuality judges the water readings of an aquarium and sets its
+// WaterQualityOK condition. Readings are adopted by the aquarium so they go
+// with it, and pruned once they leave the window.
+func (r *AquariumReconciler) reconcileWaterQuality(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+) (waterQualityReport, error) {
+	var readings funv1alpha1.WaterReadingList
+	if err := r.List(
+		ctx,
+		&readings,
+		client.InNamespace(aquarium.Namespace),
+		client.MatchingFields{waterReadingAquariumIndexKey: aquarium.Name},
+	); err != nil {
+		return waterQualityReport{}, fmt.Errorf("failed to list water readings: %w", err)
+	}
+
+	now := time.Now()
+	window := waterQualityWindow(aquarium)
+
+	var current []funv1alpha1.WaterReading
+	for i := range readings.Items {
+		reading := &readings.Items[i]
+
+		if readingTime(reading).Add(window).Before(now) {
+			log.FromContext(ctx).V(1).Info("pruning water reading", "waterReading", reading.Name)
+			if err := r.Delete(ctx, reading); client.IgnoreNotFound(err) != nil {
+				return waterQualityReport{}, fmt.Errorf("failed to prune water reading %s: %w", reading.Name, err)
+			}
+			continue
+		}
+
+		if err := r.adoptWaterReading(ctx, aquarium, reading); err != nil {
+			return waterQualityReport{}, err
+		}
+		current = append(current, *reading)
+	}
+
+	report := evaluateWaterQuality(aquarium, current, now)
+	setCondition(aquarium, WaterQualityOK, report.Status, report.Reason, report.Message)
+
+	return report, nil
+}
+
+// adoptWaterReading makes the aquarium an owner of a reading taken in it.
+func (r *AquariumReconciler) adoptWaterReading(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	reading *funv1alpha1.WaterReading,
+) error {
+	for _, ref := range reading.OwnerReferences {
+		if ref.UID == aquarium.UID {
+			return nil
+		}
+	}
+
+	patch := client.MergeFrom(reading.DeepCopy())
+	if err := controllerutil.SetOwnerReference(aquarium, reading, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Patch(ctx, reading, patch); err != nil {
+		return fmt.Errorf("failed to adopt water reading %s: %w", reading.Name, err)
+	}
+
+	return nil
+}
+
+// evaluateWaterQuality averages the readings of every tank over the window
+// and checks the averages against the ranges of the aquarium. Readings of
+// tanks the aquarium doesn't have are ignored.
+func evaluateWaterQuality(
+	aquarium *funv1alpha1.Aquarium,
+	readings []funv1alpha1.WaterReading,
+	now time.Time,
+) waterQualityReport {
+	window := waterQualityWindow(aquarium)
+	tanks := tankCapacity(aquarium)
+
+	type sum struct {
+		total float64
+		count int
+	}
+	sums := map[int32]map[string]*sum{}
+	var oldest *time.Time
+	for i := range readings {
+		reading := &readings[i]
+		taken := readingTime(reading)
+		if reading.Spec.Tank >= tanks || taken.Add(window).Before(now) {
+			continue
+		}
+		if oldest == nil || taken.Before(*oldest) {
+			oldest = &taken
+		}
+
+		if sums[reading.Spec.Tank] == nil {
+			sums[reading.Spec.Tank] = map[string]*sum{}
+		}
+		for _, parameter := range waterParameters {
+			value := parameter.value(&reading.Spec)
+			if value == nil {
+				continue
+			}
+			s := sums[reading.Spec.Tank][parameter.name]
+			if s == nil {
+				s = &sum{}
+				sums[reading.Spec.Tank][parameter.name] = s
+			}
+			s.total += value.AsApproximateFloat64()
+			s.count++
+		}
+	}
+
+	if oldest == nil {
+		return waterQualityReport{
+			Status:  metav1.ConditionUnknown,
+			Reason:  NoWaterReadings,
+			Message: fmt.Sprintf("No water readings were taken in the last %s", window),
+		}
+	}
+	expires := oldest.Add(window)
+
+	tankIndexes := make([]int32, 0, len(sums))
+	for tank := range sums {
+		tankIndexes = append(tankIndexes, tank)
+	}
+	sort.Slice(tankIndexes, func(i, j int) bool { return tankIndexes[i] < tankIndexes[j] })
+
+	var problems []string
+	for _, tank := range tankIndexes {
+		for _, parameter := range waterParameters {
+			s := sums[tank][parameter.name]
+			if s == nil {
+				continue
+			}
+			average := s.total / float64(s.count)
+			if problem, ok := outOfRange(parameter, waterQualityRange(aquarium, parameter), average); ok {
+				problems = append(problems, fmt.Sprintf("tank %d %s", tank, problem))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return waterQualityReport{
+			Status:  metav1.ConditionFalse,
+			Reason:  WaterOutOfRange,
+			Message: strings.Join(problems, "; "),
+			Expires: &expires,
+		}
+	}
+
+	return waterQualityReport{
+		Status:  metav1.ConditionTrue,
+		Reason:  WaterInRange,
+		Message: fmt.Sprintf("The water of %d tanks is in range", len(tankIndexes)),
+		Expires: &expires,
+	}
+}
+
+// outOfRange describes how a value falls outside the range of its parameter.
+func outOfRange(parameter waterParameter, limit *funv1alpha1.Range, value float64) (string, bool) {
+	if limit == nil {
+		return "", false
+	}
+
+	if limit.Min != nil && value < limit.Min.AsApproximateFloat64() {
+		return fmt.Sprintf("%s %g%s is below %s%s",
+			parameter.name, roundReading(value), parameter.unit, limit.Min, parameter.unit), true
+	}
+	if limit.Max != nil && value > limit.Max.AsApproximateFloat64() {
+		return fmt.Sprintf("%s %g%s is above %s%s",
+			parameter.name, roundReading(value), parameter.unit, limit.Max, parameter.unit), true
+	}
+
+	return "", false
+}
+
+// roundReading rounds averaged readings to two decimals for display.
+func roundReading(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// waterQualityRange is the range a parameter must stay in, falling back to the default range.
+func waterQualityRange(aquarium *funv1alpha1.Aquarium, parameter waterParameter) *funv1alpha1.Range {
+	if limit := parameter.limit(&aquarium.Spec.WaterQuality); limit != nil {
+		return limit
+	}
+
+	return parameter.limit(&funv1alpha1.DefaultWaterQuality)
+}
+
+// waterQualityWindow is how far back the readings of an aquarium are considered.
+func waterQualityWindow(aquarium *funv1alpha1.Aquarium) time.Duration {
+	if window := aquarium.Spec.WaterQuality.Window; window != nil && window.Duration > 0 {
+		return window.Duration
+	}
+
+	return funv1alpha1.DefaultWaterQualityWindow.Duration
+}
+
+// readingTime is when a water reading was taken.
+func readingTime(reading *funv1alpha1.WaterReading) time.Time {
+	if reading.Spec.TakenAt != nil {
+		return reading.Spec.TakenAt.Time
+	}
+
+	return reading.CreationTimestamp.Time
+}
+
+// applyWaterQuality lowers the fish health by one step while the water is out of range.
+func applyWaterQuality(report healthReport, water waterQualityReport) healthReport {
+	if water.Status != metav1.ConditionFalse {
+		return report
+	}
+
+	switch report.Health {
+	case funv1alpha1.Healthy:
+		report.Health = funv1alpha1.KindOfHealthy
+	case funv1alpha1.KindOfHealthy:
+		report.Health = funv1alpha1.Unhealthy
+	default:
+		return report
+	}
+
+	report.Status = metav1.ConditionFalse
+	report.Reason = WaterOutOfRange
+	report.Message = fmt.Sprintf("The water is out of range: %s", water.Message)
+
+	return report
+}
+
+// waterReadingAquarium returns the aquarium a water reading was taken in.
+func waterReadingAquarium(obj client.Object) string {
+	if reading, ok := obj.(*funv1alpha1.WaterReading); ok {
+		return reading.Spec.Aquarium
+	}
+
+	return ""
+}
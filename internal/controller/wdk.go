@@ -2,25 +2,151 @@ package controller
 
 // Label Keys
 const (
-	AppKey    = "app"
-	LocatedAt = "located-at"
+	AppNameKey      = "app.kubernetes.io/name"
+	AppInstanceKey  = "app.kubernetes.io/instance"
+	AppManagedByKey = "app.kubernetes.io/managed-by"
+	AquariumUIDKey  = "fun.tydanny.com/aquarium-uid"
+	LocatedAt       = "located-at"
+	// FeedingScheduleKey labels the CronJobs and feeder pods of a feeding schedule.
+	FeedingScheduleKey = "fun.tydanny.com/feeding-schedule"
+	// TrackKey tells the canary tanks from the stable ones.
+	TrackKey = "fun.tydanny.com/track"
+	// ExhibitKey labels the aquaria built for an exhibit.
+	ExhibitKey = "fun.tydanny.com/exhibit"
+	// LegacyTanksKey labels the legacy ReplicaSets adopted by an aquarium with its UID.
+	LegacyTanksKey = "fun.tydanny.com/legacy-tanks"
 )
 
 // Label Values
 const (
+	AquariumAppName = "aquarium"
+	FeederAppName   = "aquarium-feeder"
+	StableTrack     = "stable"
+	CanaryTrack     = "canary"
+)
+
+// Legacy labels used as the selector of every Deployment before
+// selectors were unique per aquarium.
+const (
+	AppKey        = "app"
 	AquariumValue = "Aquarium"
 )
 
-// Field owner
-const AquariumOperator = "aquarium-operator"
+// Annotations
+const (
+	// ForceDeleteAnnotation skips draining the tanks when set to "true" on a deleted aquarium.
+	ForceDeleteAnnotation = "fun.tydanny.com/force-delete"
+	// PausedAnnotation stops the operator from changing the tanks while set to "true".
+	PausedAnnotation = "fun.tydanny.com/paused"
+	// DesiredStateAnnotation records a hash of the Deployment the operator last applied.
+	DesiredStateAnnotation = "fun.tydanny.com/desired-state"
+	// TemplateHashAnnotation records a hash of the pod template the operator last asked the tanks to run.
+	TemplateHashAnnotation = "fun.tydanny.com/template-hash"
+	// PromoteAnnotation promotes the template of the canary tanks to every tank when set to "true".
+	PromoteAnnotation = "fun.tydanny.com/promote"
+)
+
+// Finalizers
+const (
+	AquariumFinalizer = "fun.tydanny.com/teardown"
+)
+
+// Container names
+const (
+	TankContainerName   = "aquarium"
+	FeederContainerName = "feeder"
+)
+
+// Volume names
+const (
+	TankVolumeName = "water"
+)
+
+// Port names
+const (
+	TankPortName = "http"
+)
+
+// Field owners
+const (
+	AquariumOperator = "aquarium-operator"
+	ReplicasHandover = "aquarium-operator-handover"
+)
 
 // Condition Types
 const (
-	AquariumReady = "aquariumReady"
+	AquariumReady          = "aquariumReady"
+	Available              = "Available"
+	Progressing            = "Progressing"
+	Degraded               = "Degraded"
+	ReconcileError         = "ReconcileError"
+	Schedulable            = "Schedulable"
+	Placed                 = "Placed"
+	CompatibilityViolation = "CompatibilityViolation"
+	Ready                  = "Ready"
+	MissedFeeding          = "MissedFeeding"
+	WaterQualityOK         = "WaterQualityOK"
+	InMaintenance          = "InMaintenance"
+	Drifted                = "Drifted"
+	DisruptionProtected    = "DisruptionProtected"
 )
 
 // Condition Reasons
 const (
-	AquariumIsHealthy   = "AquariumIsHealthy"
-	AquariumIsUnHealthy = "AquariumIsUnHealthy"
+	AquariumIsHealthy      = "AquariumIsHealthy"
+	AquariumIsKindaHealthy = "AquariumIsKindaHealthy"
+	AquariumIsRollingOut   = "AquariumIsRollingOut"
+	AquariumIsUnHealthy    = "AquariumIsUnHealthy"
+	AquariumHealthUnknown  = "AquariumHealthUnknown"
+	RolloutInProgress      = "RolloutInProgress"
+	RolloutComplete        = "RolloutComplete"
+	ReconcileFailed        = "ReconcileFailed"
+	ReconcileSucceeded     = "ReconcileSucceeded"
+	NodesMatchLocation     = "NodesMatchLocation"
+	NoNodesMatchLocation   = "NoNodesMatchLocation"
+	LocationNotMapped      = "LocationNotMapped"
+	FishPlaced             = "FishPlaced"
+	AquariumNotFound       = "AquariumNotFound"
+	AquariumClosing        = "AquariumClosing"
+	TankNotFound           = "TankNotFound"
+	SpeciesCompatible      = "SpeciesCompatible"
+	IncompatibleSpecies    = "IncompatibleSpecies"
+	InvalidSchedule        = "InvalidSchedule"
+	FeedingsScheduled      = "FeedingsScheduled"
+	FeedingsOnSchedule     = "FeedingsOnSchedule"
+	FeedingMissed          = "FeedingMissed"
+	NoWaterReadings        = "NoWaterReadings"
+	WaterInRange           = "WaterInRange"
+	WaterOutOfRange        = "WaterOutOfRange"
+	MaintenanceWindowOpen  = "MaintenanceWindowOpen"
+	ReconciliationPaused   = "ReconciliationPaused"
+	NoMaintenance          = "NoMaintenance"
+	InvalidMaintenance     = "InvalidMaintenance"
+	NoDrift                = "NoDrift"
+	DriftDetected          = "DriftDetected"
+	DriftReverted          = "DriftReverted"
+	DriftIgnored           = "DriftIgnored"
+	DriftNotReported       = "DriftNotReported"
+	TanksProtected         = "TanksProtected"
+	TanksUnprotected       = "TanksUnprotected"
+	AquariaHealthy         = "AquariaHealthy"
+	AquariaNotHealthy      = "AquariaNotHealthy"
+)
+
+// Event Reasons
+const (
+	DeploymentCreated   = "DeploymentCreated"
+	DeploymentMigrating = "DeploymentMigrating"
+	Scaled              = "Scaled"
+	ApplyFailed         = "ApplyFailed"
+	StatusUpdateFailed  = "StatusUpdateFailed"
+	Closed              = "Closed"
+	TeardownForced      = "TeardownForced"
+	TeardownTimedOut    = "TeardownTimedOut"
+	MaintenanceStarted  = "MaintenanceStarted"
+	MaintenanceEnded    = "MaintenanceEnded"
+	DeploymentDrifted   = "DeploymentDrifted"
+	StatefulSetCreated  = "StatefulSetCreated"
+	CanaryStarted       = "CanaryStarted"
+	CanaryPromoted      = "CanaryPromoted"
 )
@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+// workloadKind is the kind of workload running the tanks of an aquarium.
+func workloadKind(aquarium *funv1alpha1.Aquarium) funv1alpha1.WorkloadKind {
+	if aquarium.Spec.WorkloadKind == "" {
+		return funv1alpha1.WorkloadDeployment
+	}
+
+	return aquarium.Spec.WorkloadKind
+}
+
+// reconcileWorkload drives the tanks of an aquarium towards the desired state
+// with the workload of its kind. Like reconcileDeployment, it returns the
+// tanks to be judged by as a Deployment.
+func (r *AquariumReconciler) reconcileWorkload(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	maintenance maintenanceState,
+	replicas *int32,
+) (*appsv1.Deployment, ctrl.Result, error) {
+	if workloadKind(aquarium) == funv1alpha1.WorkloadStatefulSet {
+		return r.reconcileStatefulSet(ctx, aquarium, maintenance, replicas)
+	}
+
+	return r.reconcileDeployment(ctx, aquarium, maintenance, replicas)
+}
+
+// reconcileStatefulSet applies the StatefulSet of an aquarium and the headless
+// Service that gives its tanks their identity. Changes made to the StatefulSet
+// by others are reverted without being reported. Paused aquaria are only
+// observed. The replicas are left alone when nil.
+func (r *AquariumReconciler) reconcileStatefulSet(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	maintenance maintenanceState,
+	replicas *int32,
+) (*appsv1.Deployment, ctrl.Result, error) {
+	var liveSts *appsv1.StatefulSet
+	var sts appsv1.StatefulSet
+	err := r.Get(ctx, client.ObjectKeyFromObject(aquarium), &sts)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, ctrl.Result{}, err
+	}
+	if err == nil {
+		liveSts = &sts
+	}
+
+	if maintenance.Paused {
+		log.FromContext(ctx).V(1).Info("aquarium is paused, leaving the tanks alone")
+		return statefulSetTanks(liveSts), ctrl.Result{}, nil
+	}
+
+	setCondition(aquarium, Drifted, metav1.ConditionUnknown, DriftNotReported,
+		"Changes made to the stateful set outside the operator are reverted without being reported")
+
+	location, err := r.getLocation(ctx, aquarium)
+	if err != nil {
+		return statefulSetTanks(liveSts), ctrl.Result{}, err
+	}
+
+	if err := r.Patch(
+		ctx,
+		newHeadlessService(aquarium),
+		client.Apply,
+		client.ForceOwnership,
+		client.FieldOwner(AquariumOperator),
+	); err != nil {
+		return statefulSetTanks(liveSts), ctrl.Result{}, fmt.Errorf("failed to apply headless service: %w", err)
+	}
+
+	desiredSts := newStatefulSet(aquarium, location, replicas, r.DefaultTankImage)
+	if err := r.Patch(
+		ctx,
+		desiredSts,
+		client.Apply,
+		client.ForceOwnership,
+		client.FieldOwner(AquariumOperator),
+	); err != nil {
+		r.events.Eventf(aquarium, corev1.EventTypeWarning, ApplyFailed, "Failed to apply stateful set: %v", err)
+		return statefulSetTanks(liveSts), ctrl.Result{}, fmt.Errorf("failed to apply stateful set: %w", err)
+	}
+
+	switch {
+	case liveSts == nil:
+		r.events.Eventf(aquarium, corev1.EventTypeNormal, StatefulSetCreated,
+			"Created stateful set %s", desiredSts.Name)
+	case liveSts.Spec.Replicas != nil && desiredSts.Spec.Replicas != nil &&
+		*liveSts.Spec.Replicas != *desiredSts.Spec.Replicas:
+		r.events.Eventf(aquarium, corev1.EventTypeNormal, Scaled,
+			"Scaled tanks from %d to %d", *liveSts.Spec.Replicas, *desiredSts.Spec.Replicas)
+	}
+
+	judged := statefulSetTanks(desiredSts)
+	if err := r.reconcileAutoscaler(ctx, aquarium, maintenance); err != nil {
+		return judged, ctrl.Result{}, err
+	}
+
+	canary := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: canaryName(aquarium)}}
+	if err := r.retireWorkloads(ctx, aquarium, judged, &appsv1.Deployment{}, canary); err != nil {
+		return judged, ctrl.Result{}, err
+	}
+	aquarium.Status.Rollout = rolloutStatus(judged, nil)
+
+	return judged, ctrl.Result{}, nil
+}
+
+// retireWorkloads deletes the workloads the tanks of an aquarium moved away
+// from, once every tank is available in the workload they moved to. Until
+// then the old tanks keep the fish company. The volumes of retired
+// StatefulSet tanks are kept.
+func (r *AquariumReconciler) retireWorkloads(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+	tanks *appsv1.Deployment,
+	workloads ...client.Object,
+) error {
+	if tanks == nil || tanks.Spec.Replicas == nil || tanks.Status.AvailableReplicas < *tanks.Spec.Replicas {
+		return nil
+	}
+
+	return r.deleteOwned(ctx, aquarium, workloads...)
+}
+
+// statefulSetTanks presents the tanks of a StatefulSet as a Deployment, which
+// the health, status and metrics of an aquarium are judged by.
+func statefulSetTanks(sts *appsv1.StatefulSet) *appsv1.Deployment {
+	if sts == nil {
+		return nil
+	}
+
+	return &appsv1.Deployment{
+		ObjectMeta: *sts.ObjectMeta.DeepCopy(),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: sts.Spec.Replicas,
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: sts.Status.ObservedGeneration,
+			Replicas:           sts.Status.Replicas,
+			UpdatedReplicas:    sts.Status.UpdatedReplicas,
+			ReadyReplicas:      sts.Status.ReadyReplicas,
+			AvailableReplicas:  sts.Status.AvailableReplicas,
+		},
+	}
+}
+
+// headlessServiceName names the headless Service of StatefulSet tanks.
+func headlessServiceName(aquarium *funv1alpha1.Aquarium) string {
+	return aquarium.Name + "-tanks"
+}
+
+// newStatefulSet builds the StatefulSet running the tanks of an aquarium, with
+// a volume claim for every tank when the aquarium has storage. The replicas
+// are left out when they are nil.
+func newStatefulSet(
+	aquarium *funv1alpha1.Aquarium,
+	location *funv1alpha1.Location,
+	replicas *int32,
+	defaultImage string,
+) *appsv1.StatefulSet {
+	sts := &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "StatefulSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            aquarium.Name,
+			Namespace:       aquarium.Namespace,
+			Labels:          aquariumLabels(aquarium),
+			OwnerReferences: []metav1.OwnerReference{ownerReference(aquarium)},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selectorLabels(aquarium),
+			},
+			ServiceName: headlessServiceName(aquarium),
+			Template:    newPodTemplate(aquarium, location, defaultImage),
+		},
+	}
+
+	if storage := aquarium.Spec.Storage; storage != nil {
+		mountPath := storage.MountPath
+		if mountPath == "" {
+			mountPath = funv1alpha1.DefaultTankMountPath
+		}
+
+		tank := &sts.Spec.Template.Spec.Containers[0]
+		tank.VolumeMounts = append(tank.VolumeMounts, corev1.VolumeMount{
+			Name:      TankVolumeName,
+			MountPath: mountPath,
+		})
+		sts.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   TankVolumeName,
+				Labels: aquariumLabels(aquarium),
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: storage.StorageClassName,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: storage.Size},
+				},
+			},
+		}}
+	}
+
+	return sts
+}
+
+// newHeadlessService builds the Service that gives StatefulSet tanks a stable
+// network identity, like <aquarium>-0.<aquarium>-tanks.
+func newHeadlessService(aquarium *funv1alpha1.Aquarium) *corev1.Service {
+	service := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            headlessServiceName(aquarium),
+			Namespace:       aquarium.Namespace,
+			Labels:          aquariumLabels(aquarium),
+			OwnerReferences: []metav1.OwnerReference{ownerReference(aquarium)},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  selectorLabels(aquarium),
+		},
+	}
+
+	if exposure := aquarium.Spec.Exposure; exposure != nil {
+		service.Spec.Ports = []corev1.ServicePort{{
+			Name:       TankPortName,
+			Protocol:   corev1.ProtocolTCP,
+			Port:       exposure.Port,
+			TargetPort: intstr.FromString(TankPortName),
+		}}
+	}
+
+	return service
+}
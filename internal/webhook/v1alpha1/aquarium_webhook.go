@@ -0,0 +1,431 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+	"github.com/tydanny/aquarium-operator/internal/controller"
+)
+
+// log is for logging in this package.
+var aquariumlog = logf.Log.WithName("aquarium-resource")
+
+// SetupAquariumWebhookWithManager registers the webhooks for Aquarium in the manager.
+// Aquaria are looked up against the API server rather than the cache, which only
+// holds the namespaces the operator watches.
+func SetupAquariumWebhookWithManager(mgr ctrl.Manager, validator *AquariumCustomValidator) error {
+	if validator.Client == nil {
+		validator.Client = mgr.GetAPIReader()
+	}
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&funv1alpha1.Aquarium{}).
+		WithDefaulter(&AquariumCustomDefaulter{}).
+		WithValidator(validator).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-fun-tydanny-com-v1alpha1-aquarium,mutating=true,failurePolicy=fail,sideEffects=None,groups=fun.tydanny.com,resources=aquaria,verbs=create;update,versions=v1alpha1,name=maquarium.kb.io,admissionReviewVersions=v1
+
+// AquariumCustomDefaulter sets default values on Aquaria as they are created or updated.
+type AquariumCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &AquariumCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type.
+func (d *AquariumCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	aquarium, ok := obj.(*funv1alpha1.Aquarium)
+	if !ok {
+		return fmt.Errorf("expected an Aquarium object but got %T", obj)
+	}
+	aquariumlog.Info("default", "name", aquarium.Name)
+
+	if aquarium.Spec.Location == "" {
+		aquarium.Spec.Location = funv1alpha1.DefaultLocation
+	}
+
+	// An empty image is left for the operator to fill in with its configured
+	// default tank image.
+	tank := &aquarium.Spec.Tank
+	if tank.ImagePullPolicy == "" {
+		tank.ImagePullPolicy = corev1.PullIfNotPresent
+	}
+
+	if aquarium.Spec.Health.DegradedThreshold == nil {
+		threshold := funv1alpha1.DefaultDegradedThreshold
+		aquarium.Spec.Health.DegradedThreshold = &threshold
+	}
+
+	if autoscaling := aquarium.Spec.Autoscaling; autoscaling != nil {
+		if autoscaling.MinTanks == nil {
+			autoscaling.MinTanks = pointer.Int32(1)
+		}
+		if autoscaling.TargetCPUUtilizationPercentage == nil {
+			autoscaling.TargetCPUUtilizationPercentage = pointer.Int32(funv1alpha1.DefaultTargetCPUUtilizationPercentage)
+		}
+	}
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-fun-tydanny-com-v1alpha1-aquarium,mutating=false,failurePolicy=fail,sideEffects=None,groups=fun.tydanny.com,resources=aquaria,verbs=create;update,versions=v1alpha1,name=vaquarium.kb.io,admissionReviewVersions=v1
+
+// AquariumCustomValidator validates Aquaria as they are created or updated.
+type AquariumCustomValidator struct {
+	// Client is used to look up the other aquaria in a namespace.
+	Client client.Reader
+
+	// AllowedLocations is the list of locations aquaria may be built at.
+	// Any location is allowed when it is empty.
+	AllowedLocations []string
+
+	// MaxTanksPerNamespace caps the number of tanks across all aquaria of a
+	// namespace. There is no cap when it is zero.
+	MaxTanksPerNamespace int32
+}
+
+var _ webhook.CustomValidator = &AquariumCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type.
+func (v *AquariumCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	aquarium, ok := obj.(*funv1alpha1.Aquarium)
+	if !ok {
+		return nil, fmt.Errorf("expected an Aquarium object but got %T", obj)
+	}
+	aquariumlog.Info("validate create", "name", aquarium.Name)
+
+	return v.validateAquarium(ctx, aquarium, nil)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type.
+func (v *AquariumCustomValidator) ValidateUpdate(
+	ctx context.Context,
+	oldObj, newObj runtime.Object,
+) (admission.Warnings, error) {
+	aquarium, ok := newObj.(*funv1alpha1.Aquarium)
+	if !ok {
+		return nil, fmt.Errorf("expected an Aquarium object for the newObj but got %T", newObj)
+	}
+	oldAquarium, ok := oldObj.(*funv1alpha1.Aquarium)
+	if !ok {
+		return nil, fmt.Errorf("expected an Aquarium object for the oldObj but got %T", oldObj)
+	}
+	aquariumlog.Info("validate update", "name", aquarium.Name)
+
+	return v.validateAquarium(ctx, aquarium, oldAquarium)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type.
+func (v *AquariumCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateAquarium checks an aquarium against the validator's policies. oldAquarium
+// is nil on creation. Aquaria being deleted aren't validated, so their teardown,
+// like the removal of their finalizer, is never held up by a policy.
+func (v *AquariumCustomValidator) validateAquarium(
+	ctx context.Context,
+	aquarium, oldAquarium *funv1alpha1.Aquarium,
+) (admission.Warnings, error) {
+	if !aquarium.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if oldAquarium != nil && oldAquarium.Spec.Location != aquarium.Spec.Location {
+		allErrs = append(allErrs, field.Forbidden(
+			specPath.Child("location"),
+			"the location of an aquarium can't be changed after it is built",
+		))
+	}
+
+	// Aquaria built before a location was disallowed may stay where they are.
+	if oldAquarium == nil && len(v.AllowedLocations) > 0 && !contains(v.AllowedLocations, aquarium.Spec.Location) {
+		allErrs = append(allErrs, field.NotSupported(
+			specPath.Child("location"),
+			aquarium.Spec.Location,
+			v.AllowedLocations,
+		))
+	}
+
+	if autoscaling := aquarium.Spec.Autoscaling; autoscaling != nil &&
+		autoscaling.MinTanks != nil && *autoscaling.MinTanks > autoscaling.MaxTanks {
+		allErrs = append(allErrs, field.Invalid(
+			specPath.Child("autoscaling", "min_tanks"),
+			*autoscaling.MinTanks,
+			"must be less than or equal to max_tanks",
+		))
+	}
+
+	allErrs = append(allErrs, validateSchedules(aquarium)...)
+	allErrs = append(allErrs, validateRollout(aquarium)...)
+
+	if aquarium.Spec.WorkloadKind == funv1alpha1.WorkloadStatefulSet &&
+		aquarium.Spec.DriftPolicy != "" && aquarium.Spec.DriftPolicy != funv1alpha1.DriftEnforce {
+		allErrs = append(allErrs, field.NotSupported(
+			specPath.Child("drift_policy"),
+			aquarium.Spec.DriftPolicy,
+			[]string{string(funv1alpha1.DriftEnforce)},
+		))
+	}
+
+	if oldAquarium != nil && aquarium.Spec.WorkloadKind == funv1alpha1.WorkloadStatefulSet &&
+		oldAquarium.Spec.WorkloadKind == funv1alpha1.WorkloadStatefulSet &&
+		!equality.Semantic.DeepEqual(aquarium.Spec.Storage, oldAquarium.Spec.Storage) {
+		allErrs = append(allErrs, field.Forbidden(
+			specPath.Child("storage"),
+			"the storage of StatefulSet tanks can't be changed while they run",
+		))
+	}
+
+	if exposure := aquarium.Spec.Exposure; exposure != nil &&
+		exposure.Route == funv1alpha1.RouteHTTPRoute && exposure.Gateway == nil {
+		allErrs = append(allErrs, field.Required(
+			specPath.Child("exposure", "gateway"),
+			"an HTTPRoute must attach to a gateway",
+		))
+	}
+
+	// Updates that don't add tanks are allowed even when the namespace is over
+	// the limit, like after the limit was lowered.
+	if v.MaxTanksPerNamespace > 0 && (oldAquarium == nil || maxTanks(aquarium) > maxTanks(oldAquarium)) {
+		tankErr, err := v.validateNamespaceTanks(ctx, aquarium)
+		if err != nil {
+			return nil, err
+		}
+		if tankErr != nil {
+			allErrs = append(allErrs, tankErr)
+		}
+	}
+
+	if len(allErrs) > 0 {
+		return nil, apierrors.NewInvalid(
+			funv1alpha1.GroupVersion.WithKind("Aquarium").GroupKind(),
+			aquarium.Name,
+			allErrs,
+		)
+	}
+
+	return aquariumWarnings(aquarium), nil
+}
+
+// validateSchedules makes sure the cron expressions of the schedule and the
+// maintenance windows parse.
+func validateSchedules(aquarium *funv1alpha1.Aquarium) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for i, entry := range aquarium.Spec.Schedule {
+		if _, err := controller.ParseCron(entry.Start, entry.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(
+				field.NewPath("spec", "schedule").Index(i).Child("start"), entry.Start, err.Error()))
+		}
+	}
+
+	maintenance := aquarium.Spec.Maintenance
+	for i, window := range maintenance.Windows {
+		if _, err := controller.ParseCron(window.Schedule, maintenance.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(
+				field.NewPath("spec", "maintenance", "windows").Index(i).Child("schedule"), window.Schedule, err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+// validateRollout makes sure the rolling update limits can be used: Recreate
+// rollouts and StatefulSet tanks have none, and at least one of them must let
+// the tanks roll. StatefulSet tanks can't be rolled out any other way.
+func validateRollout(aquarium *funv1alpha1.Aquarium) field.ErrorList {
+	var allErrs field.ErrorList
+	rollout := aquarium.Spec.Rollout
+	path := field.NewPath("spec", "rollout")
+
+	if aquarium.Spec.WorkloadKind == funv1alpha1.WorkloadStatefulSet {
+		if rollout.Strategy != "" && rollout.Strategy != funv1alpha1.RolloutRollingUpdate {
+			allErrs = append(allErrs, field.Forbidden(
+				path.Child("strategy"), "StatefulSet tanks can only be rolled out with a RollingUpdate"))
+		}
+		if rollout.MaxSurge != nil || rollout.MaxUnavailable != nil {
+			allErrs = append(allErrs, field.Forbidden(path, "StatefulSet tanks roll one at a time"))
+		}
+
+		return allErrs
+	}
+
+	if rollout.Strategy == funv1alpha1.RolloutRecreate {
+		if rollout.MaxSurge != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("max_surge"), "may not be set for a Recreate rollout"))
+		}
+		if rollout.MaxUnavailable != nil {
+			allErrs = append(allErrs, field.Forbidden(
+				path.Child("max_unavailable"), "may not be set for a Recreate rollout"))
+		}
+
+		return allErrs
+	}
+
+	if isZero(rollout.MaxSurge) && isZero(rollout.MaxUnavailable) {
+		allErrs = append(allErrs, field.Invalid(
+			path.Child("max_unavailable"), rollout.MaxUnavailable.String(), "may not be 0 when max_surge is 0"))
+	}
+
+	return allErrs
+}
+
+// validateNamespaceTanks makes sure the aquarium doesn't push its namespace over
+// the tank limit. Aquaria count with the most tanks they may scale up to, except
+// for aquaria being deleted.
+func (v *AquariumCustomValidator) validateNamespaceTanks(
+	ctx context.Context,
+	aquarium *funv1alpha1.Aquarium,
+) (*field.Error, error) {
+	var aquaria funv1alpha1.AquariumList
+	if err := v.Client.List(ctx, &aquaria, client.InNamespace(aquarium.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list aquaria in namespace %s: %w", aquarium.Namespace, err)
+	}
+
+	total := maxTanks(aquarium)
+	for i := range aquaria.Items {
+		if aquaria.Items[i].Name == aquarium.Name || !aquaria.Items[i].DeletionTimestamp.IsZero() {
+			continue
+		}
+		total += maxTanks(&aquaria.Items[i])
+	}
+
+	if total <= v.MaxTanksPerNamespace {
+		return nil, nil
+	}
+
+	path := field.NewPath("spec", "num_tanks")
+	if aquarium.Spec.Autoscaling != nil {
+		path = field.NewPath("spec", "autoscaling", "max_tanks")
+	}
+
+	return field.Forbidden(path, fmt.Sprintf(
+		"namespace %s would have %d tanks, more than the %d allowed",
+		aquarium.Namespace, total, v.MaxTanksPerNamespace,
+	)), nil
+}
+
+// aquariumWarnings points out valid but likely unintended configurations.
+func aquariumWarnings(aquarium *funv1alpha1.Aquarium) admission.Warnings {
+	var warnings admission.Warnings
+	tank := aquarium.Spec.Tank
+
+	if aquarium.Spec.Autoscaling != nil {
+		warnings = append(warnings, "spec.num_tanks is ignored while spec.autoscaling is set")
+		if len(aquarium.Spec.Schedule) > 0 {
+			warnings = append(warnings, "spec.schedule is ignored while spec.autoscaling is set")
+		}
+
+		if _, ok := tank.Resources.Requests[corev1.ResourceCPU]; !ok {
+			warnings = append(warnings,
+				"spec.tank.resources.requests.cpu is not set, so the autoscaler can't measure CPU utilization")
+		}
+	}
+
+	if aquarium.Spec.Storage != nil && aquarium.Spec.WorkloadKind != funv1alpha1.WorkloadStatefulSet {
+		warnings = append(warnings, "spec.storage is ignored unless spec.workload_kind is StatefulSet")
+	}
+
+	if exposure := aquarium.Spec.Exposure; exposure != nil {
+		if exposure.IngressClassName != nil && exposure.Route != funv1alpha1.RouteIngress {
+			warnings = append(warnings, "spec.exposure.ingress_class_name is ignored unless spec.exposure.route is Ingress")
+		}
+		if exposure.Gateway != nil && exposure.Route != funv1alpha1.RouteHTTPRoute {
+			warnings = append(warnings, "spec.exposure.gateway is ignored unless spec.exposure.route is HTTPRoute")
+		}
+	}
+
+	if minAvailable := aquarium.Spec.Disruption.MinAvailableTanks; minAvailable != nil &&
+		*minAvailable >= maxTanks(aquarium) {
+		warnings = append(warnings, fmt.Sprintf(
+			"spec.disruption.min_available_tanks %d would keep every tank running, every tank but one is kept instead",
+			*minAvailable))
+	}
+
+	if image := tank.Image; image != "" && image != funv1alpha1.DefaultTankImage && !isPinned(image) {
+		warnings = append(warnings, fmt.Sprintf(
+			"spec.tank.image %q is not pinned to a tag, tanks may run different versions", image))
+	}
+
+	return warnings
+}
+
+func maxTanks(aquarium *funv1alpha1.Aquarium) int32 {
+	if aquarium.Spec.Autoscaling != nil {
+		return aquarium.Spec.Autoscaling.MaxTanks
+	}
+
+	tanks := aquarium.Spec.NumTanks
+	for _, entry := range aquarium.Spec.Schedule {
+		if entry.Tanks > tanks {
+			tanks = entry.Tanks
+		}
+	}
+
+	return tanks
+}
+
+// isPinned reports whether an image reference has a digest or a tag other than latest.
+func isPinned(image string) bool {
+	if strings.Contains(image, "@") {
+		return true
+	}
+
+	name := image[strings.LastIndex(image, "/")+1:]
+	return strings.Contains(name, ":") && !strings.HasSuffix(name, ":latest")
+}
+
+// isZero reports whether a rolling update limit is set to 0 or 0%.
+func isZero(value *intstr.IntOrString) bool {
+	if value == nil {
+		return false
+	}
+
+	scaled, err := intstr.GetScaledValueFromIntOrPercent(value, 100, true)
+	return err == nil && scaled == 0
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,234 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+	webhookfunv1alpha1 "github.com/tydanny/aquarium-operator/internal/webhook/v1alpha1"
+)
+
+var _ = Describe("Aquarium Webhook", func() {
+	newAquarium := func(name string, spec funv1alpha1.AquariumSpec) *funv1alpha1.Aquarium {
+		return &funv1alpha1.Aquarium{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: WebhookNamespace,
+			},
+			Spec: spec,
+		}
+	}
+
+	Context("When creating an Aquarium under the defaulting webhook", func() {
+		It("should fill in the defaults", func() {
+			ctx := context.Background()
+
+			aquarium := newAquarium("defaulted-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks:    1,
+				Autoscaling: &funv1alpha1.AutoscalingSpec{MaxTanks: 2},
+			})
+			Expect(k8sClient.Create(ctx, aquarium)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, aquarium)
+
+			Expect(aquarium.Spec.Location).To(Equal(funv1alpha1.DefaultLocation))
+			Expect(aquarium.Spec.Tank.Image).To(BeEmpty(), "the operator resolves the default tank image")
+			Expect(aquarium.Spec.Tank.Command).To(BeEmpty())
+			Expect(aquarium.Spec.Health.DegradedThreshold).To(HaveValue(Equal(funv1alpha1.DefaultDegradedThreshold)))
+			Expect(aquarium.Spec.Autoscaling.MinTanks).To(HaveValue(Equal(int32(1))))
+		})
+	})
+
+	Context("When creating or updating an Aquarium under the validating webhook", func() {
+		It("should deny locations that aren't allowed", func() {
+			ctx := context.Background()
+
+			aquarium := newAquarium("atlantis-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks: 1,
+				Location: "Atlantis",
+			})
+			err := k8sClient.Create(ctx, aquarium)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.location"))
+		})
+
+		It("should deny moving an aquarium to another location", func() {
+			ctx := context.Background()
+
+			aquarium := newAquarium("moving-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks: 1,
+				Location: "Atlanta",
+			})
+			Expect(k8sClient.Create(ctx, aquarium)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, aquarium)
+
+			aquarium.Spec.Location = funv1alpha1.DefaultLocation
+			err := k8sClient.Update(ctx, aquarium)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("can't be changed"))
+
+			By("still allowing other changes")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(aquarium), aquarium)).To(Succeed())
+			aquarium.Spec.NumTanks = 2
+			Expect(k8sClient.Update(ctx, aquarium)).To(Succeed())
+		})
+
+		It("should deny going over the tank limit of the namespace", func() {
+			ctx := context.Background()
+
+			aquarium := newAquarium("big-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks: MaxTanksPerNamespace - 2,
+			})
+			Expect(k8sClient.Create(ctx, aquarium)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, aquarium)
+
+			overflow := newAquarium("overflow-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks: 3,
+			})
+			err := k8sClient.Create(ctx, overflow)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.num_tanks"))
+
+			By("allowing aquaria that fit")
+			overflow.Spec.NumTanks = 2
+			Expect(k8sClient.Create(ctx, overflow)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, overflow)
+		})
+
+		It("should only hold aquaria that add tanks to the limit of the namespace", func() {
+			ctx := context.Background()
+
+			By("Leaving the tanks of aquaria being deleted out")
+			closing := newAquarium("closing-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks: MaxTanksPerNamespace - 2,
+			})
+			closing.Finalizers = []string{"fun.tydanny.com/teardown"}
+			Expect(k8sClient.Create(ctx, closing)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, closing)).To(Succeed())
+
+			opening := newAquarium("opening-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks: 3,
+			})
+			Expect(k8sClient.Create(ctx, opening)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, opening)
+
+			By("Letting the finalizer of an aquarium over the limit go")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(closing), closing)).To(Succeed())
+			closing.Finalizers = nil
+			Expect(k8sClient.Update(ctx, closing)).To(Succeed())
+
+			By("Allowing updates that don't add tanks once the limit is lowered")
+			validator := &webhookfunv1alpha1.AquariumCustomValidator{Client: k8sClient, MaxTanksPerNamespace: 2}
+			relabeled := opening.DeepCopy()
+			relabeled.Labels = map[string]string{"wing": "east"}
+			_, err := validator.ValidateUpdate(ctx, opening, relabeled)
+			Expect(err).NotTo(HaveOccurred())
+
+			grown := opening.DeepCopy()
+			grown.Spec.NumTanks = 4
+			_, err = validator.ValidateUpdate(ctx, opening, grown)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+
+		It("should deny autoscaling with more minimum than maximum tanks", func() {
+			ctx := context.Background()
+
+			aquarium := newAquarium("backwards-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks: 1,
+				Autoscaling: &funv1alpha1.AutoscalingSpec{
+					MinTanks: pointer.Int32(3),
+					MaxTanks: 2,
+				},
+			})
+			err := k8sClient.Create(ctx, aquarium)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("min_tanks"))
+		})
+
+		It("should deny schedules that aren't cron expressions", func() {
+			ctx := context.Background()
+
+			aquarium := newAquarium("unscheduled-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks: 1,
+				Location: "Atlanta",
+				Schedule: []funv1alpha1.ScheduleEntry{{
+					Name:     "opening-hours",
+					Start:    "at nine",
+					Duration: metav1.Duration{Duration: 8 * time.Hour},
+					Tanks:    3,
+				}},
+			})
+			err := k8sClient.Create(ctx, aquarium)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.schedule[0].start"))
+		})
+
+		It("should deny rolling updates that can't roll", func() {
+			ctx := context.Background()
+
+			zero := intstr.FromInt(0)
+			aquarium := newAquarium("stuck-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks: 1,
+				Location: "Atlanta",
+				Rollout: funv1alpha1.RolloutSpec{
+					MaxSurge:       &zero,
+					MaxUnavailable: &zero,
+				},
+			})
+			err := k8sClient.Create(ctx, aquarium)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.rollout.max_unavailable"))
+		})
+
+		It("should deny HTTPRoutes without a gateway", func() {
+			ctx := context.Background()
+
+			aquarium := newAquarium("unrouted-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks: 1,
+				Location: "Atlanta",
+				Exposure: &funv1alpha1.ExposureSpec{
+					Port:  8080,
+					Route: funv1alpha1.RouteHTTPRoute,
+				},
+			})
+			err := k8sClient.Create(ctx, aquarium)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.exposure.gateway"))
+		})
+
+		It("should deny canaries of StatefulSet tanks", func() {
+			ctx := context.Background()
+
+			aquarium := newAquarium("stateful-canary-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks:     1,
+				Location:     "Atlanta",
+				WorkloadKind: funv1alpha1.WorkloadStatefulSet,
+				Rollout:      funv1alpha1.RolloutSpec{Strategy: funv1alpha1.RolloutCanary},
+			})
+			err := k8sClient.Create(ctx, aquarium)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.rollout.strategy"))
+		})
+
+		It("should deny drift policies StatefulSet tanks don't follow", func() {
+			ctx := context.Background()
+
+			aquarium := newAquarium("stateful-drift-aquarium", funv1alpha1.AquariumSpec{
+				NumTanks:     1,
+				Location:     "Atlanta",
+				WorkloadKind: funv1alpha1.WorkloadStatefulSet,
+				DriftPolicy:  funv1alpha1.DriftReport,
+			})
+			err := k8sClient.Create(ctx, aquarium)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.drift_policy"))
+		})
+	})
+})
@@ -0,0 +1,147 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+	"github.com/tydanny/aquarium-operator/internal/controller"
+)
+
+// log is for logging in this package.
+var fishlog = logf.Log.WithName("fish-resource")
+
+// SetupFishWebhookWithManager registers the webhooks for Fish in the manager.
+// Fish are validated against the API server rather than the cache so fish
+// added in quick succession can't slip past each other.
+func SetupFishWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&funv1alpha1.Fish{}).
+		WithValidator(&FishCustomValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-fun-tydanny-com-v1alpha1-fish,mutating=false,failurePolicy=fail,sideEffects=None,groups=fun.tydanny.com,resources=fish,verbs=create;update,versions=v1alpha1,name=vfish.kb.io,admissionReviewVersions=v1
+
+// FishCustomValidator rejects fish that can't live with the fish already in their aquarium.
+type FishCustomValidator struct {
+	// Client is used to look up the aquarium, its fish and the species profiles.
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &FishCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type.
+func (v *FishCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	fish, ok := obj.(*funv1alpha1.Fish)
+	if !ok {
+		return nil, fmt.Errorf("expected a Fish object but got %T", obj)
+	}
+	fishlog.Info("validate create", "name", fish.Name)
+
+	return nil, v.validateCompatibility(ctx, fish)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type.
+func (v *FishCustomValidator) ValidateUpdate(
+	ctx context.Context,
+	_, newObj runtime.Object,
+) (admission.Warnings, error) {
+	fish, ok := newObj.(*funv1alpha1.Fish)
+	if !ok {
+		return nil, fmt.Errorf("expected a Fish object for the newObj but got %T", newObj)
+	}
+	fishlog.Info("validate update", "name", fish.Name)
+
+	return nil, v.validateCompatibility(ctx, fish)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type.
+func (v *FishCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateCompatibility rejects fish that would break the species compatibility
+// rules of their aquarium. Combinations that were already incompatible don't
+// hold up unrelated fish. Placements in missing aquaria are left to the
+// controller to refuse.
+func (v *FishCustomValidator) validateCompatibility(ctx context.Context, fish *funv1alpha1.Fish) error {
+	var aquarium funv1alpha1.Aquarium
+	key := client.ObjectKey{Namespace: fish.Namespace, Name: fish.Spec.Aquarium}
+	if err := v.Client.Get(ctx, key, &aquarium); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get aquarium %s: %w", fish.Spec.Aquarium, err)
+	}
+
+	var fishList funv1alpha1.FishList
+	if err := v.Client.List(ctx, &fishList, client.InNamespace(fish.Namespace)); err != nil {
+		return fmt.Errorf("failed to list fish in namespace %s: %w", fish.Namespace, err)
+	}
+
+	var profiles funv1alpha1.SpeciesProfileList
+	if err := v.Client.List(ctx, &profiles); err != nil {
+		return fmt.Errorf("failed to list species profiles: %w", err)
+	}
+	bySpecies := controller.ProfilesBySpecies(profiles.Items)
+
+	var others []funv1alpha1.Fish
+	for _, item := range fishList.Items {
+		if item.Name != fish.Name {
+			others = append(others, item)
+		}
+	}
+	others = controller.PlacedFish(&aquarium, others)
+
+	existing := map[string]bool{}
+	for _, violation := range controller.EvaluateCompatibility(&aquarium, others, bySpecies) {
+		existing[violation.Message] = true
+	}
+
+	var allErrs field.ErrorList
+	for _, violation := range controller.EvaluateCompatibility(
+		&aquarium,
+		controller.PlacedFish(&aquarium, append(others, *fish)),
+		bySpecies,
+	) {
+		if !existing[violation.Message] {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "species"), violation.Message))
+		}
+	}
+
+	if len(allErrs) > 0 {
+		return apierrors.NewInvalid(
+			funv1alpha1.GroupVersion.WithKind("Fish").GroupKind(),
+			fish.Name,
+			allErrs,
+		)
+	}
+
+	return nil
+}
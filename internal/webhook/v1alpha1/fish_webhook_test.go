@@ -0,0 +1,81 @@
+package v1alpha1_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	funv1alpha1 "github.com/tydanny/aquarium-operator/api/v1alpha1"
+)
+
+var _ = Describe("Fish Webhook", func() {
+	Context("When placing Fish under the validating webhook", func() {
+		It("should deny fish that can't live with the fish in their tank", func() {
+			ctx := context.Background()
+
+			for _, profile := range []*funv1alpha1.SpeciesProfile{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "great-white"},
+					Spec: funv1alpha1.SpeciesProfileSpec{
+						Temperament: funv1alpha1.Predator,
+						WaterType:   funv1alpha1.Saltwater,
+						Temperature: funv1alpha1.TemperatureRange{MinCelsius: 12, MaxCelsius: 24},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "sea-lion"},
+					Spec: funv1alpha1.SpeciesProfileSpec{
+						Temperament: funv1alpha1.Peaceful,
+						WaterType:   funv1alpha1.Saltwater,
+						Temperature: funv1alpha1.TemperatureRange{MinCelsius: 10, MaxCelsius: 20},
+					},
+				},
+			} {
+				Expect(k8sClient.Create(ctx, profile)).To(Succeed())
+				DeferCleanup(k8sClient.Delete, profile)
+			}
+
+			aquarium := &funv1alpha1.Aquarium{
+				ObjectMeta: metav1.ObjectMeta{Name: "shark-aquarium", Namespace: WebhookNamespace},
+				Spec:       funv1alpha1.AquariumSpec{NumTanks: 2},
+			}
+			Expect(k8sClient.Create(ctx, aquarium)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, aquarium)
+
+			newFish := func(name, species string, tank int32) *funv1alpha1.Fish {
+				return &funv1alpha1.Fish{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: WebhookNamespace},
+					Spec: funv1alpha1.FishSpec{
+						Aquarium: aquarium.Name,
+						Species:  species,
+						Count:    1,
+						Tank:     pointer.Int32(tank),
+					},
+				}
+			}
+
+			shark := newFish("great-white", "great-white", 0)
+			Expect(k8sClient.Create(ctx, shark)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, shark)
+
+			By("denying prey in the predator's tank")
+			err := k8sClient.Create(ctx, newFish("sea-lion", "sea-lion", 0))
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("great-white preys on sea-lion"))
+
+			By("allowing prey in another tank")
+			seaLion := newFish("sea-lion", "sea-lion", 1)
+			Expect(k8sClient.Create(ctx, seaLion)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, seaLion)
+
+			By("denying moving the prey into the predator's tank")
+			seaLion.Spec.Tank = pointer.Int32(0)
+			err = k8sClient.Update(ctx, seaLion)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+	})
+})